@@ -3,6 +3,7 @@ module github.com/k14s/ytt
 go 1.13
 
 require (
+	github.com/BurntSushi/toml v0.4.1
 	github.com/aws/aws-lambda-go v1.8.2
 	github.com/cppforlife/cobrautil v0.0.0-20200514214827-bb86e6965d72
 	github.com/cppforlife/go-cli-ui v0.0.0-20200505234325-512793797f05
@@ -10,6 +11,6 @@ require (
 	github.com/k14s/difflib v0.0.0-20201117154628-0c031775bf57
 	github.com/k14s/starlark-go v0.0.0-20200720175618-3a5c849cc368
 	github.com/spf13/cobra v1.0.0
-	github.com/spf13/pflag v1.0.5 // indirect
+	github.com/spf13/pflag v1.0.5
 	gopkg.in/check.v1 v1.0.0-20190902080502-41f04d3bba15
 )
@@ -0,0 +1,260 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	cmdtpl "github.com/k14s/ytt/pkg/cmd/template"
+	"github.com/k14s/ytt/pkg/cmd/ui"
+	"github.com/k14s/ytt/pkg/files"
+	"github.com/k14s/ytt/pkg/schema"
+	"github.com/k14s/ytt/pkg/workspace"
+	"github.com/k14s/ytt/pkg/yamlmeta"
+	"github.com/spf13/cobra"
+)
+
+const dataValuesMeta = "@data/values"
+
+func NewSchemaCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Work with data values schemas",
+	}
+	cmd.AddCommand(NewSchemaInferCmd(NewSchemaInferOptions()))
+	cmd.AddCommand(NewSchemaDiffCmd(NewSchemaDiffOptions()))
+	return cmd
+}
+
+type SchemaInferOptions struct {
+	Files      []string
+	StrictYAML bool
+	Debug      bool
+}
+
+func NewSchemaInferOptions() *SchemaInferOptions {
+	return &SchemaInferOptions{}
+}
+
+func NewSchemaInferCmd(o *SchemaInferOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "infer",
+		Short: "Infer schema from data values files",
+		Long: `Infer schema from data values files.
+
+Documents annotated with @data/values are used; files without such documents are treated as plain YAML.
+Shapes of all data values are merged; scalar values found first become defaults.`,
+		RunE: func(_ *cobra.Command, _ []string) error { return o.Run() },
+	}
+	cmd.Flags().StringArrayVarP(&o.Files, "file", "f", nil, "File (ie local path, HTTP URL, -) (can be specified multiple times)")
+	cmd.Flags().BoolVarP(&o.StrictYAML, "strict", "s", false, "Configure to use _strict_ YAML subset")
+	cmd.Flags().BoolVar(&o.Debug, "debug", false, "Enable debug output")
+	return cmd
+}
+
+func (o *SchemaInferOptions) Run() error {
+	ui := ui.NewTTY(o.Debug)
+	t1 := time.Now()
+
+	defer func() {
+		ui.Debugf("total: %s\n", time.Now().Sub(t1))
+	}()
+
+	filesToProcess, err := files.NewSortedFilesFromPaths(o.Files, files.SymlinkAllowOpts{})
+	if err != nil {
+		return err
+	}
+
+	schemaStr, err := o.RunWithFiles(filesToProcess)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprint(os.Stdout, schemaStr)
+	return nil
+}
+
+func (o *SchemaInferOptions) RunWithFiles(filesToProcess []*files.File) (string, error) {
+	var valuesDocs []*yamlmeta.Document
+
+	for _, file := range filesToProcess {
+		if file.Type() != files.TypeYAML {
+			continue
+		}
+
+		data, err := file.Bytes()
+		if err != nil {
+			return "", err
+		}
+
+		docSet, err := yamlmeta.NewParser(yamlmeta.ParserOpts{Strict: o.StrictYAML}).ParseBytes(data, file.RelativePath())
+		if err != nil {
+			return "", err
+		}
+
+		valuesDocs = append(valuesDocs, o.dataValuesDocs(docSet)...)
+	}
+
+	schemaDoc, err := schema.InferDocument(valuesDocs)
+	if err != nil {
+		return "", fmt.Errorf("Inferring schema: %s", err)
+	}
+
+	schemaBytes, err := schemaDoc.AsYAMLBytesWithComments()
+	if err != nil {
+		return "", fmt.Errorf("Printing schema: %s", err)
+	}
+	return string(schemaBytes), nil
+}
+
+// dataValuesDocs returns documents annotated with @data/values or, if there are none, all documents
+func (o *SchemaInferOptions) dataValuesDocs(docSet *yamlmeta.DocumentSet) []*yamlmeta.Document {
+	var annotatedDocs []*yamlmeta.Document
+
+	for _, doc := range docSet.Items {
+		for _, meta := range doc.Metas {
+			if strings.TrimSpace(meta.Data) == dataValuesMeta {
+				annotatedDocs = append(annotatedDocs, doc)
+				break
+			}
+		}
+	}
+
+	if len(annotatedDocs) > 0 {
+		return annotatedDocs
+	}
+	return docSet.Items
+}
+
+type SchemaDiffOptions struct {
+	OldFiles      []string
+	NewFiles      []string
+	FileMarksOpts cmdtpl.FileMarksOpts
+	StrictYAML    bool
+	Debug         bool
+}
+
+func NewSchemaDiffOptions() *SchemaDiffOptions {
+	return &SchemaDiffOptions{}
+}
+
+func NewSchemaDiffCmd(o *SchemaDiffOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Compare data values schemas of two versions of a library",
+		Long: `Compare data values schemas of two versions of a library.
+
+Changes that may cause data values accepted by the old schema to be rejected by the new one
+(removed keys, narrowed types, keys no longer nullable, new values that must be provided) are breaking.
+Added validation rules are potentially breaking, since whether they reject data values depends on the values;
+rules given as functions are compared only by function name, so changes to their bodies are not reported.
+Exits with non-zero status if any breaking changes are found (potentially breaking changes are only reported).`,
+		RunE: func(_ *cobra.Command, _ []string) error { return o.Run() },
+	}
+	cmd.Flags().StringArrayVar(&o.OldFiles, "old", nil, "Files of old version (ie local path, HTTP URL) (can be specified multiple times)")
+	cmd.Flags().StringArrayVar(&o.NewFiles, "new", nil, "Files of new version (ie local path, HTTP URL) (can be specified multiple times)")
+	o.FileMarksOpts.Set(cmd)
+	cmd.Flags().BoolVarP(&o.StrictYAML, "strict", "s", false, "Configure to use _strict_ YAML subset")
+	cmd.Flags().BoolVar(&o.Debug, "debug", false, "Enable debug output")
+	return cmd
+}
+
+func (o *SchemaDiffOptions) Run() error {
+	ui := ui.NewTTY(o.Debug)
+	t1 := time.Now()
+
+	defer func() {
+		ui.Debugf("total: %s\n", time.Now().Sub(t1))
+	}()
+
+	if len(o.OldFiles) == 0 || len(o.NewFiles) == 0 {
+		return fmt.Errorf("Expected both --old and --new to be specified")
+	}
+
+	oldFiles, err := files.NewSortedFilesFromPaths(o.OldFiles, files.SymlinkAllowOpts{})
+	if err != nil {
+		return err
+	}
+
+	newFiles, err := files.NewSortedFilesFromPaths(o.NewFiles, files.SymlinkAllowOpts{})
+	if err != nil {
+		return err
+	}
+
+	report, err := o.RunWithFiles(oldFiles, newFiles, ui)
+	fmt.Fprint(os.Stdout, report)
+	return err
+}
+
+// RunWithFiles returns a report of changes between schemas of old and new files.
+// Error is returned if schemas cannot be loaded or if any breaking changes were found.
+func (o *SchemaDiffOptions) RunWithFiles(oldFiles, newFiles []*files.File, ui ui.UI) (string, error) {
+	oldSchema, err := o.schema(oldFiles, ui)
+	if err != nil {
+		return "", fmt.Errorf("Loading old schema: %s", err)
+	}
+
+	newSchema, err := o.schema(newFiles, ui)
+	if err != nil {
+		return "", fmt.Errorf("Loading new schema: %s", err)
+	}
+
+	changes := schema.Diff(oldSchema.Allowed, newSchema.Allowed)
+	if len(changes) == 0 {
+		return "No schema changes found\n", nil
+	}
+
+	var report strings.Builder
+	counts := map[schema.ChangeKind]int{}
+
+	for _, change := range changes {
+		counts[change.Kind]++
+		fmt.Fprintf(&report, "%-13s %s: %s (%s)\n", string(change.Kind)+":", change.Path, change.Message, change.Position.AsCompactString())
+	}
+	fmt.Fprintf(&report, "\n%d breaking, %d potentially breaking, %d non-breaking change(s)\n",
+		counts[schema.ChangeBreaking], counts[schema.ChangePotentiallyBreaking], counts[schema.ChangeNonBreaking])
+
+	breaking := counts[schema.ChangeBreaking]
+
+	if breaking > 0 {
+		return report.String(), fmt.Errorf("Found %d breaking schema change(s)", breaking)
+	}
+	return report.String(), nil
+}
+
+func (o *SchemaDiffOptions) schema(filesToProcess []*files.File, ui ui.UI) (*schema.DocumentSchema, error) {
+	return loadDocumentSchema(filesToProcess, o.FileMarksOpts, o.StrictYAML, ui)
+}
+
+// loadDocumentSchema returns schema of the root library made of given files
+func loadDocumentSchema(filesToProcess []*files.File, fileMarksOpts cmdtpl.FileMarksOpts, strictYAML bool, ui ui.UI) (*schema.DocumentSchema, error) {
+	filesToProcess, err := fileMarksOpts.Apply(filesToProcess)
+	if err != nil {
+		return nil, err
+	}
+
+	rootLibrary := workspace.NewRootLibrary(filesToProcess)
+
+	libraryExecutionFactory := workspace.NewLibraryExecutionFactory(ui, workspace.TemplateLoaderOpts{
+		StrictYAML:    strictYAML,
+		SchemaEnabled: true,
+	})
+
+	libraryCtx := workspace.LibraryExecutionContext{Current: rootLibrary, Root: rootLibrary}
+
+	currSchema, err := libraryExecutionFactory.New(libraryCtx).Schema()
+	if err != nil {
+		return nil, err
+	}
+
+	docSchema, ok := currSchema.(*schema.DocumentSchema)
+	if !ok {
+		return nil, fmt.Errorf("no schema document was found " +
+			"(hint: include a document annotated with @schema/match data_values=True)")
+	}
+	return docSchema, nil
+}
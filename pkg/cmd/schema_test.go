@@ -0,0 +1,366 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/k14s/difflib"
+	"github.com/k14s/ytt/pkg/cmd"
+	cmdtpl "github.com/k14s/ytt/pkg/cmd/template"
+	"github.com/k14s/ytt/pkg/cmd/ui"
+	"github.com/k14s/ytt/pkg/files"
+)
+
+func TestSchemaInfer(t *testing.T) {
+	dataValuesYAML1 := `#@ load("@ytt:data", "data")
+#@data/values
+---
+name: app
+replicas: 3
+ratio: 0.5
+hosts:
+- name: a
+  port: 80
+- name: b
+  weight: 2
+tags: []
+db:
+  user: admin
+`
+	dataValuesYAML2 := `#@data/values
+---
+name: null
+replicas: "3"
+db:
+  password: ""
+`
+	plainYAML := `labels:
+  app: foo
+`
+	expectedSchema := `#@schema/match data_values=True
+---
+#@schema/nullable
+name: app
+#@schema/type any=True
+replicas: 3
+#@schema/type any=True
+ratio: 0.5
+hosts:
+- name: a
+  port: 80
+  weight: 2
+tags:
+#@schema/type any=True
+- null
+db:
+  user: admin
+  password: ""
+labels:
+  app: foo
+`
+
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("values1.yml", []byte(dataValuesYAML1))),
+		files.MustNewFileFromSource(files.NewBytesSource("values2.yml", []byte(dataValuesYAML2))),
+		files.MustNewFileFromSource(files.NewBytesSource("plain.yml", []byte(plainYAML))),
+	})
+
+	schemaStr, err := cmd.NewSchemaInferOptions().RunWithFiles(filesToProcess)
+	if err != nil {
+		t.Fatalf("Expected inferring schema to succeed, but was error: %s", err)
+	}
+
+	if schemaStr != expectedSchema {
+		diff := difflib.PPDiff(strings.Split(schemaStr, "\n"), strings.Split(expectedSchema, "\n"))
+		t.Fatalf("Expected inferred schema to match, differences:\n%s", diff)
+	}
+
+	t.Run("accepts data values it was inferred from", func(t *testing.T) {
+		templateYAML := `#@ load("@ytt:data", "data")
+---
+values: #@ data.values
+`
+		expected := `values:
+  name: app
+  replicas: 3
+  ratio: 0.5
+  hosts:
+  - name: a
+    port: 80
+    weight: 2
+  - name: b
+    weight: 2
+    port: 80
+  tags: []
+  db:
+    user: admin
+    password: ""
+  labels:
+    app: foo
+`
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaStr))),
+			files.MustNewFileFromSource(files.NewBytesSource("values1.yml", []byte(dataValuesYAML1))),
+			files.MustNewFileFromSource(files.NewBytesSource("template.yml", []byte(templateYAML))),
+		})
+
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+		if out.Err != nil {
+			t.Fatalf("Expected RunWithFiles to succeed, but was error: %s", out.Err)
+		}
+
+		outStr := string(out.Files[0].Bytes())
+		if outStr != expected {
+			diff := difflib.PPDiff(strings.Split(outStr, "\n"), strings.Split(expected, "\n"))
+			t.Errorf("Expected output to include data values, differences:\n%s", diff)
+		}
+	})
+
+	t.Run("writes schema that is accepted for empty maps and non-string keys", func(t *testing.T) {
+		dataValuesYAML := `#@data/values
+---
+"on":
+  push: {}
+on: yes
+1: one
+nested:
+  "key: with colon": ""
+`
+		expectedSchema := `#@schema/match data_values=True
+---
+"on":
+  push: {}
+true: true
+1: one
+nested:
+  'key: with colon': ""
+`
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("values.yml", []byte(dataValuesYAML))),
+		})
+
+		schemaStr, err := cmd.NewSchemaInferOptions().RunWithFiles(filesToProcess)
+		if err != nil {
+			t.Fatalf("Expected inferring schema to succeed, but was error: %s", err)
+		}
+
+		if schemaStr != expectedSchema {
+			diff := difflib.PPDiff(strings.Split(schemaStr, "\n"), strings.Split(expectedSchema, "\n"))
+			t.Fatalf("Expected inferred schema to match, differences:\n%s", diff)
+		}
+
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+		opts.InspectSchema = true
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaStr))),
+		})}, ui.NewTTY(false))
+		if out.Err != nil {
+			t.Fatalf("Expected inspecting inferred schema to succeed, but was error: %s", out.Err)
+		}
+	})
+}
+
+func TestSchemaDiff(t *testing.T) {
+	oldSchemaYAML := `#@schema/match data_values=True
+---
+name: app
+replicas: 1
+port: 80
+#@schema/nullable
+namespace: ""
+legacy: true
+#@schema/type one_of=["debug", "info"]
+logLevel: info
+hosts:
+- name: ""
+`
+
+	t.Run("classifies breaking and non-breaking changes", func(t *testing.T) {
+		newSchemaYAML := `#@schema/match data_values=True
+---
+name: app2
+replicas: 2
+#@schema/type one_of=[int, str]
+port: 80
+namespace: ""
+#@schema/type one_of=["debug", "info", "warn"]
+logLevel: info
+hosts:
+- name: ""
+  port: 443
+#@schema/validation min_len=1
+token: ""
+`
+		oldFiles := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("old.yml", []byte(oldSchemaYAML))),
+		})
+		newFiles := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("new.yml", []byte(newSchemaYAML))),
+		})
+
+		expectedReport := `non-breaking: name: default changed from "app" to "app2" (new.yml:3)
+non-breaking: replicas: default changed from 1 to 2 (new.yml:4)
+non-breaking: port: type widened from integer to one of: integer, string (new.yml:6)
+breaking:     namespace: no longer nullable (new.yml:7)
+non-breaking: namespace: default changed from null to "" (new.yml:7)
+breaking:     legacy: removed (old.yml:8)
+non-breaking: logLevel: type widened from one of: "debug", "info" to one of: "debug", "info", "warn" (new.yml:9)
+non-breaking: hosts[].port: added with default 443 (new.yml:12)
+breaking:     token: added without a default (value is required) (new.yml:14)
+
+3 breaking, 0 potentially breaking, 6 non-breaking change(s)
+`
+
+		report, err := cmd.NewSchemaDiffOptions().RunWithFiles(oldFiles, newFiles, ui.NewTTY(false))
+		if err == nil {
+			t.Fatalf("Expected diff to fail due to breaking changes")
+		}
+		if err.Error() != "Found 3 breaking schema change(s)" {
+			t.Fatalf("Expected error to count breaking changes, but was: %s", err)
+		}
+
+		if report != expectedReport {
+			diff := difflib.PPDiff(strings.Split(report, "\n"), strings.Split(expectedReport, "\n"))
+			t.Fatalf("Expected report to match, differences:\n%s", diff)
+		}
+	})
+
+	t.Run("reports changes of required values", func(t *testing.T) {
+		oldSchemaYAML := `#@schema/match data_values=True
+---
+name: app
+#@schema/required
+token: ""
+`
+		newSchemaYAML := `#@schema/match data_values=True
+---
+#@schema/required
+name: app
+token: ""
+#@schema/required
+region: ""
+`
+		oldFiles := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("old.yml", []byte(oldSchemaYAML))),
+		})
+		newFiles := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("new.yml", []byte(newSchemaYAML))),
+		})
+
+		expectedReport := `breaking:     name: became required (new.yml:4)
+non-breaking: token: no longer required (defaults to "") (new.yml:5)
+breaking:     region: added without a default (value is required) (new.yml:7)
+
+2 breaking, 0 potentially breaking, 1 non-breaking change(s)
+`
+
+		report, err := cmd.NewSchemaDiffOptions().RunWithFiles(oldFiles, newFiles, ui.NewTTY(false))
+		if err == nil || err.Error() != "Found 2 breaking schema change(s)" {
+			t.Fatalf("Expected diff to fail due to breaking changes, but was: %v", err)
+		}
+
+		if report != expectedReport {
+			diff := difflib.PPDiff(strings.Split(report, "\n"), strings.Split(expectedReport, "\n"))
+			t.Fatalf("Expected report to match, differences:\n%s", diff)
+		}
+	})
+
+	t.Run("reports added validation rules as potentially breaking", func(t *testing.T) {
+		oldSchemaYAML := `#@schema/match data_values=True
+---
+#@schema/validation min=1
+port: 80
+#@schema/validation min_len=1
+name: app
+region: ""
+hosts:
+- ""
+`
+		newSchemaYAML := `#@schema/match data_values=True
+---
+#@schema/validation min=1, max=65535
+port: 80
+name: app
+#@schema/validation min_len=1
+region: ""
+hosts:
+#@schema/validation format="hostname"
+- ""
+`
+		oldFiles := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("old.yml", []byte(oldSchemaYAML))),
+		})
+		newFiles := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("new.yml", []byte(newSchemaYAML))),
+		})
+
+		expectedReport := `potentially breaking: port: validation added: a number less than or equal to 65535 (new.yml:4)
+non-breaking: name: validation removed: a length of at least 1 (new.yml:5)
+potentially breaking: region: validation added: a length of at least 1 (new.yml:7)
+breaking:     region: default "" no longer satisfies validation (value is required) (new.yml:7)
+potentially breaking: hosts[]: validation added: a value of format 'hostname' (new.yml:10)
+
+1 breaking, 3 potentially breaking, 1 non-breaking change(s)
+`
+
+		report, err := cmd.NewSchemaDiffOptions().RunWithFiles(oldFiles, newFiles, ui.NewTTY(false))
+		if err == nil || err.Error() != "Found 1 breaking schema change(s)" {
+			t.Fatalf("Expected diff to fail due to breaking changes, but was: %v", err)
+		}
+
+		if report != expectedReport {
+			diff := difflib.PPDiff(strings.Split(report, "\n"), strings.Split(expectedReport, "\n"))
+			t.Fatalf("Expected report to match, differences:\n%s", diff)
+		}
+	})
+
+	t.Run("succeeds when there are no breaking changes", func(t *testing.T) {
+		newSchemaYAML := oldSchemaYAML + `enabled: false
+`
+		oldFiles := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("old.yml", []byte(oldSchemaYAML))),
+		})
+		newFiles := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("new.yml", []byte(newSchemaYAML))),
+		})
+
+		expectedReport := `non-breaking: enabled: added with default false (new.yml:13)
+
+0 breaking, 0 potentially breaking, 1 non-breaking change(s)
+`
+
+		report, err := cmd.NewSchemaDiffOptions().RunWithFiles(oldFiles, newFiles, ui.NewTTY(false))
+		if err != nil {
+			t.Fatalf("Expected diff to succeed, but was error: %s", err)
+		}
+
+		if report != expectedReport {
+			diff := difflib.PPDiff(strings.Split(report, "\n"), strings.Split(expectedReport, "\n"))
+			t.Fatalf("Expected report to match, differences:\n%s", diff)
+		}
+	})
+
+	t.Run("fails when schema is missing", func(t *testing.T) {
+		oldFiles := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("old.yml", []byte(oldSchemaYAML))),
+		})
+		newFiles := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("new.yml", []byte("name: app\n"))),
+		})
+
+		_, err := cmd.NewSchemaDiffOptions().RunWithFiles(oldFiles, newFiles, ui.NewTTY(false))
+		if err == nil || !strings.Contains(err.Error(), "Loading new schema: no schema document was found") {
+			t.Fatalf("Expected diff to fail due to missing schema, but was: %v", err)
+		}
+	})
+}
@@ -4,6 +4,7 @@
 package template
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/k14s/ytt/pkg/cmd/ui"
@@ -22,6 +23,8 @@ type Options struct {
 	Debug         bool
 	InspectFiles  bool
 	SchemaEnabled bool
+	InspectSchema bool
+	AllowClock    bool
 
 	BulkFilesSourceOpts    BulkFilesSourceOpts
 	RegularFilesSourceOpts RegularFilesSourceOpts
@@ -36,7 +39,9 @@ type Input struct {
 type Output struct {
 	Files  []files.OutputFile
 	DocSet *yamlmeta.DocumentSet
-	Err    error
+	// DocSet includes comments (e.g. data values provenance) that should be printed
+	DocSetWithComments bool
+	Err                error
 }
 
 type FileSource interface {
@@ -67,6 +72,9 @@ func NewCmd(o *Options) *cobra.Command {
 	cmd.Flags().BoolVar(&o.Debug, "debug", false, "Enable debug output")
 	cmd.Flags().BoolVar(&o.InspectFiles, "files-inspect", false, "Inspect files")
 	cmd.Flags().BoolVar(&o.SchemaEnabled, "enable-experiment-schema", false, "Enable experimental schema features")
+	cmd.Flags().BoolVar(&o.AllowClock, "allow-clock", false, "Allow templates to access current time via @ytt:time (output is no longer reproducible)")
+	cmd.Flags().BoolVar(&o.InspectSchema, "data-values-schema-inspect", false,
+		"Inspect data values schema (as OpenAPI v3 by default; use --output json-schema for JSON Schema)")
 
 	o.BulkFilesSourceOpts.Set(cmd)
 	o.RegularFilesSourceOpts.Set(cmd)
@@ -112,63 +120,92 @@ func (o *Options) RunWithFiles(in Input, ui ui.UI) Output {
 		return o.inspectFiles(rootLibrary)
 	}
 
+	if !o.InspectSchema && o.isSchemaOutputType() {
+		return Output{Err: fmt.Errorf("Output type '%s' requires --data-values-schema-inspect", o.RegularFilesSourceOpts.OutputType)}
+	}
+
+	if o.DataValuesFlags.InspectProvenance {
+		if !o.DataValuesFlags.Inspect && len(o.DataValuesFlags.InspectLibrary) == 0 {
+			return Output{Err: fmt.Errorf("Flag --provenance requires --data-values-inspect or --data-values-inspect-library")}
+		}
+		switch o.RegularFilesSourceOpts.OutputType {
+		case regularFilesOutputTypeJSON, regularFilesOutputTypePos:
+			return Output{Err: fmt.Errorf("Flag --provenance requires output type '%s'", regularFilesOutputTypeYAML)}
+		}
+	}
+
 	valuesOverlays, libraryValuesOverlays, err := o.DataValuesFlags.AsOverlays(o.StrictYAML)
 	if err != nil {
 		return Output{Err: err}
 	}
 
-	libraryExecutionFactory := workspace.NewLibraryExecutionFactory(ui, workspace.TemplateLoaderOpts{
+	var arrayMerge schema.ArrayMergeStrategy
+	if len(o.DataValuesFlags.ArrayMerge) > 0 {
+		arrayMerge, err = schema.NewArrayMergeStrategy(o.DataValuesFlags.ArrayMerge)
+		if err != nil {
+			return Output{Err: fmt.Errorf("Flag --data-values-array-merge: %s", err)}
+		}
+	}
+
+	redactingUI := newRedactingUI(ui)
+
+	libraryExecutionFactory := workspace.NewLibraryExecutionFactory(redactingUI, workspace.TemplateLoaderOpts{
 		IgnoreUnknownComments:   o.IgnoreUnknownComments,
 		ImplicitMapKeyOverrides: o.ImplicitMapKeyOverrides,
 		StrictYAML:              o.StrictYAML,
+		SchemaEnabled:           o.SchemaEnabled,
+		ArrayMergeStrategy:      arrayMerge,
+		AllowClock:              o.AllowClock,
 	})
 
+	var libraryValuesInspector *workspace.LibraryValuesInspector
+	if len(o.DataValuesFlags.InspectLibrary) > 0 {
+		libraryValuesInspector, err = workspace.NewLibraryValuesInspector(o.DataValuesFlags.InspectLibrary)
+		if err != nil {
+			return Output{Err: fmt.Errorf("Inspecting library data values: %s", err)}
+		}
+		libraryExecutionFactory = libraryExecutionFactory.WithValuesInspector(libraryValuesInspector)
+	}
+
 	libraryCtx := workspace.LibraryExecutionContext{Current: rootLibrary, Root: rootLibrary}
 	libraryLoader := libraryExecutionFactory.New(libraryCtx)
 
-	schemaDocs, err := libraryLoader.Schemas()
+	currSchema, err := libraryLoader.Schema()
 	if err != nil {
 		return Output{Err: err}
 	}
 
-	var currSchema workspace.Schema
-	var values *workspace.DataValues
-	var libraryValues []*workspace.DataValues
-
-	if o.SchemaEnabled {
-		if len(schemaDocs) > 0 {
-			currSchema, err = schema.NewDocumentSchema(schemaDocs[0])
-			if err != nil {
-				return Output{Err: err}
-			}
-		} else {
-			currSchema = schema.NullSchema{}
-		}
-	} else {
-		if len(schemaDocs) > 0 {
-			ui.Warnf("Warning: schema document was detected, but schema experiment flag is not enabled. Did you mean to include --enable-experiment-schema?\n")
-		}
-		currSchema = &schema.AnySchema{}
+	if o.InspectSchema {
+		return o.inspectSchema(currSchema)
 	}
 
-	values, libraryValues, err = libraryLoader.Values(valuesOverlays, currSchema)
+	values, libraryValues, err := libraryLoader.Values(valuesOverlays, currSchema)
 	if err != nil {
 		return Output{Err: err}
 	}
 
 	libraryValues = append(libraryValues, libraryValuesOverlays...)
 
-	if o.DataValuesFlags.Inspect {
-		return Output{
-			DocSet: &yamlmeta.DocumentSet{
-				Items: []*yamlmeta.Document{values.Doc},
-			},
-		}
+	// libraries' data values are only known once they are evaluated
+	values.Sensitive.Include(libraryExecutionFactory.LibrarySensitiveValues())
+	redactingUI.setRedactFunc(values.Sensitive.Redact)
+
+	if o.DataValuesFlags.Inspect && libraryValuesInspector == nil {
+		return o.inspectDataValues([]*workspace.DataValues{values})
 	}
 
 	result, err := libraryLoader.Eval(values, libraryValues)
 	if err != nil {
-		return Output{Err: err}
+		return Output{Err: values.Sensitive.RedactError(err)}
+	}
+
+	if libraryValuesInspector != nil {
+		if len(libraryValuesInspector.Values()) == 0 {
+			return Output{Err: fmt.Errorf("Inspecting library data values: Expected library '%s' to be evaluated, but it was not "+
+				"(hint: data values of a library are only calculated once library.get(...) is evaluated, e.g. via eval(), data_values() or export())",
+				o.DataValuesFlags.InspectLibrary)}
+		}
+		return o.inspectDataValues(libraryValuesInspector.Values())
 	}
 
 	return Output{Files: result.Files, DocSet: result.DocSet}
@@ -201,3 +238,48 @@ func (o *Options) inspectFiles(rootLibrary *workspace.Library) Output {
 		},
 	}
 }
+
+func (o *Options) inspectDataValues(valuess []*workspace.DataValues) Output {
+	var docs []*yamlmeta.Document
+	for _, values := range valuess {
+		doc := values.Sensitive.RedactDocument(values.Doc)
+		if o.DataValuesFlags.InspectProvenance {
+			doc = values.Provenance.AnnotateDocument(doc)
+		}
+		docs = append(docs, doc)
+	}
+	return Output{
+		DocSet:             &yamlmeta.DocumentSet{Items: docs},
+		DocSetWithComments: o.DataValuesFlags.InspectProvenance,
+	}
+}
+
+func (o *Options) inspectSchema(currSchema workspace.Schema) Output {
+	docSchema, ok := currSchema.(*schema.DocumentSchema)
+	if !ok {
+		return Output{Err: fmt.Errorf("Inspecting data values schema: no schema document was found " +
+			"(hint: enable schema with --enable-experiment-schema and include a document annotated with @schema/match data_values=True)")}
+	}
+
+	var doc *yamlmeta.Document
+	if o.RegularFilesSourceOpts.OutputType == regularFilesOutputTypeJSONSchema {
+		doc = schema.NewJSONSchemaDocument(docSchema.Allowed).AsDocument()
+	} else {
+		doc = schema.NewOpenAPIDocument(docSchema.Allowed).AsDocument()
+	}
+
+	return Output{
+		DocSet: &yamlmeta.DocumentSet{
+			Items: []*yamlmeta.Document{doc},
+		},
+	}
+}
+
+func (o *Options) isSchemaOutputType() bool {
+	switch o.RegularFilesSourceOpts.OutputType {
+	case regularFilesOutputTypeOpenAPI, regularFilesOutputTypeJSONSchema:
+		return true
+	default:
+		return false
+	}
+}
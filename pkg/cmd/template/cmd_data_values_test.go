@@ -4,11 +4,19 @@
 package template_test
 
 import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	cmdtpl "github.com/k14s/ytt/pkg/cmd/template"
 	"github.com/k14s/ytt/pkg/cmd/ui"
 	"github.com/k14s/ytt/pkg/files"
+	"github.com/k14s/ytt/pkg/yamlmeta"
+	"github.com/spf13/cobra"
 )
 
 func TestDataValues(t *testing.T) {
@@ -630,3 +638,888 @@ nested_val: nested_from_env
 		t.Fatalf("Expected output file to have specific data, but was: >>>%s<<< vs >>>%s<<<", file.Bytes(), expectedYAMLTplData)
 	}
 }
+
+func TestDataValuesSensitive(t *testing.T) {
+	yamlTplData := []byte(`
+#@ load("@ytt:data", "data")
+#@ load("@ytt:assert", "assert")
+user: #@ data.values.db.user
+password: #@ data.values.db.password if len(data.values.db.password) > 8 else assert.fail("password '{}' is too short".format(data.values.db.password))`)
+
+	yamlData := []byte(`
+#@data/values
+---
+db:
+  user: admin
+  #@data/sensitive
+  password: changeme`)
+
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("tpl.yml", yamlTplData)),
+		files.MustNewFileFromSource(files.NewBytesSource("data.yml", yamlData)),
+	})
+
+	t.Run("masks sensitive values when inspecting data values", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.DataValuesFlags.KVsFromStrings = []string{"db.password=s3cr3t-passw0rd"}
+		opts.DataValuesFlags.Inspect = true
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+		if out.Err != nil {
+			t.Fatalf("Expected RunWithFiles to succeed, but was error: %s", out.Err)
+		}
+
+		outBytes, err := out.DocSet.AsBytes()
+		if err != nil {
+			t.Fatalf("Expected marshaling data values to succeed, but was error: %s", err)
+		}
+
+		expectedOut := `db:
+  user: admin
+  password: (redacted)
+`
+		if string(outBytes) != expectedOut {
+			t.Fatalf("Expected data values to have sensitive values masked, but was: >>>%s<<<", outBytes)
+		}
+	})
+
+	t.Run("masks sensitive values in errors", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+		if out.Err == nil {
+			t.Fatalf("Expected RunWithFiles to fail")
+		}
+
+		if strings.Contains(out.Err.Error(), "changeme") {
+			t.Fatalf("Expected error to not include sensitive value, but was: >>>%s<<<", out.Err)
+		}
+		if !strings.Contains(out.Err.Error(), "password '(redacted)' is too short") {
+			t.Fatalf("Expected error to include masked value, but was: >>>%s<<<", out.Err)
+		}
+	})
+
+	t.Run("does not mask short sensitive values within unrelated text", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.DataValuesFlags.KVsFromStrings = []string{"db.password=5"}
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+		if out.Err == nil {
+			t.Fatalf("Expected RunWithFiles to fail")
+		}
+
+		if !strings.Contains(out.Err.Error(), "tpl.yml:5") {
+			t.Fatalf("Expected error to include line number, but was: >>>%s<<<", out.Err)
+		}
+	})
+
+	t.Run("masks sensitive values in debug output but not in templates' output", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.DataValuesFlags.KVsFromStrings = []string{"db.password=s3cr3t-passw0rd"}
+
+		stderr := &bytes.Buffer{}
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewCustomWriterTTY(true, nil, stderr))
+		if out.Err != nil {
+			t.Fatalf("Expected RunWithFiles to succeed, but was error: %s", out.Err)
+		}
+
+		if strings.Contains(stderr.String(), "s3cr3t-passw0rd") || !strings.Contains(stderr.String(), "password: (redacted)") {
+			t.Fatalf("Expected debug output to have sensitive value masked, but was: >>>%s<<<", stderr)
+		}
+		if !strings.Contains(string(out.Files[0].Bytes()), "password: s3cr3t-passw0rd") {
+			t.Fatalf("Expected output file to include sensitive value, but was: >>>%s<<<", out.Files[0].Bytes())
+		}
+	})
+}
+
+func TestDataValuesProvenance(t *testing.T) {
+	schemaYAML := `#@schema/match data_values=True
+---
+replicas: 1
+name: app
+db:
+  user: admin
+  port: 5432
+hosts:
+- ""
+tags:
+- ""
+#@schema/type any=True
+labels: {}
+routes:
+- path: ""
+  port: 80
+motd: ""
+`
+	dataValuesYAML := `#@data/values
+---
+replicas: 2
+db:
+  user: root
+hosts:
+- a.com
+- b.com
+routes:
+- path: /
+motd: |
+  hello
+  world
+`
+
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+		files.MustNewFileFromSource(files.NewBytesSource("values.yml", []byte(dataValuesYAML))),
+	})
+
+	t.Run("annotates each value with sources that set it", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+		opts.DataValuesFlags.EnvFromStrings = []string{"DVS"}
+		opts.DataValuesFlags.EnvironFunc = func() []string { return []string{"DVS_db__port=6000"} }
+		opts.DataValuesFlags.KVsFromStrings = []string{"replicas=3"}
+		opts.DataValuesFlags.Inspect = true
+		opts.DataValuesFlags.InspectProvenance = true
+
+		expectedOutput := `replicas: 3 # schema.yml:3 (schema default), values.yml:3, key 'replicas' (kv arg):1
+name: app # schema.yml:4 (schema default)
+db:
+  user: root # schema.yml:6 (schema default), values.yml:5
+  port: 6000 # schema.yml:7 (schema default), key 'db.port' (env var 'DVS_db__port'):1
+hosts:
+- a.com # values.yml:7
+- b.com # values.yml:8
+tags: [] # schema.yml:10 (schema default)
+labels: {} # schema.yml:13 (schema default)
+routes:
+- path: / # values.yml:10
+  port: 80 # schema.yml:16
+motd: | # schema.yml:17 (schema default), values.yml:11
+  hello
+  world
+`
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+		if out.Err != nil {
+			t.Fatalf("Expected RunWithFiles to succeed, but was error: %s", out.Err)
+		}
+
+		stdout := &bytes.Buffer{}
+		err := cmdtpl.NewRegularFilesSource(cmdtpl.RegularFilesSourceOpts{OutputType: "yaml"}, ui.NewCustomWriterTTY(false, stdout, nil)).Output(out)
+		if err != nil {
+			t.Fatalf("Expected printing output to succeed, but was error: %s", err)
+		}
+
+		if stdout.String() != expectedOutput {
+			t.Fatalf("Expected data values to be annotated with provenance, but was: >>>%s<<< vs >>>%s<<<", stdout.String(), expectedOutput)
+		}
+	})
+
+	t.Run("prints same data as without provenance", func(t *testing.T) {
+		inspect := func(provenance bool) []byte {
+			opts := cmdtpl.NewOptions()
+			opts.SchemaEnabled = true
+			opts.DataValuesFlags.Inspect = true
+			opts.DataValuesFlags.InspectProvenance = provenance
+
+			out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+			if out.Err != nil {
+				t.Fatalf("Expected RunWithFiles to succeed, but was error: %s", out.Err)
+			}
+
+			stdout := &bytes.Buffer{}
+			err := cmdtpl.NewRegularFilesSource(cmdtpl.RegularFilesSourceOpts{OutputType: "yaml"}, ui.NewCustomWriterTTY(false, stdout, nil)).Output(out)
+			if err != nil {
+				t.Fatalf("Expected printing output to succeed, but was error: %s", err)
+			}
+			return stdout.Bytes()
+		}
+
+		var withProvenance, withoutProvenance interface{}
+
+		err := yamlmeta.PlainUnmarshal(inspect(true), &withProvenance)
+		if err != nil {
+			t.Fatalf("Expected parsing output to succeed, but was error: %s", err)
+		}
+		err = yamlmeta.PlainUnmarshal(inspect(false), &withoutProvenance)
+		if err != nil {
+			t.Fatalf("Expected parsing output to succeed, but was error: %s", err)
+		}
+
+		if !reflect.DeepEqual(withProvenance, withoutProvenance) {
+			t.Fatalf("Expected data values to match, but was: %#v vs %#v", withProvenance, withoutProvenance)
+		}
+	})
+
+	t.Run("requires data values to be inspected", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.DataValuesFlags.InspectProvenance = true
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+		if out.Err == nil || out.Err.Error() != "Flag --provenance requires --data-values-inspect or --data-values-inspect-library" {
+			t.Fatalf("Expected RunWithFiles to fail, but was: %v", out.Err)
+		}
+	})
+}
+
+func TestDataValuesInspectLibrary(t *testing.T) {
+	tplYAML := `#@ load("@ytt:template", "template")
+#@ load("@ytt:library", "library")
+
+#@ lib = library.get("lib1", alias="inst1").with_data_values({"replicas": 2})
+--- #@ template.replace(lib.eval())
+`
+	refValuesYAML := `#@library/ref "@~inst1"
+#@data/values
+---
+name: from-ref
+`
+	libValuesYAML := `#@data/values
+---
+name: lib
+replicas: 1
+tags: []
+`
+	libTplYAML := `#@ load("@ytt:data", "data")
+---
+name: #@ data.values.name
+`
+
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("tpl.yml", []byte(tplYAML))),
+		files.MustNewFileFromSource(files.NewBytesSource("values.yml", []byte(refValuesYAML))),
+		files.MustNewFileFromSource(files.NewBytesSource("_ytt_lib/lib1/values.yml", []byte(libValuesYAML))),
+		files.MustNewFileFromSource(files.NewBytesSource("_ytt_lib/lib1/tpl.yml", []byte(libTplYAML))),
+	})
+
+	t.Run("prints final data values of library as JSON", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.RegularFilesSourceOpts.OutputType = "json"
+		opts.DataValuesFlags.InspectLibrary = "@~inst1"
+		opts.DataValuesFlags.KVsFromYAML = []string{"@lib1:tags=[a]"}
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+		if out.Err != nil {
+			t.Fatalf("Expected RunWithFiles to succeed, but was error: %s", out.Err)
+		}
+
+		stdout := &bytes.Buffer{}
+		err := cmdtpl.NewRegularFilesSource(opts.RegularFilesSourceOpts, ui.NewCustomWriterTTY(false, stdout, nil)).Output(out)
+		if err != nil {
+			t.Fatalf("Expected printing output to succeed, but was error: %s", err)
+		}
+
+		expectedOutput := `{"name":"from-ref","replicas":2,"tags":["a"]}`
+		if stdout.String() != expectedOutput {
+			t.Fatalf("Expected library data values, but was: >>>%s<<< vs >>>%s<<<", stdout.String(), expectedOutput)
+		}
+	})
+
+	t.Run("fails when library was not evaluated", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.DataValuesFlags.InspectLibrary = "@lib2"
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+		if out.Err == nil || !strings.Contains(out.Err.Error(), "Expected library '@lib2' to be evaluated, but it was not") {
+			t.Fatalf("Expected RunWithFiles to fail, but was: %v", out.Err)
+		}
+	})
+}
+
+func TestDataValuesFromPlainFiles(t *testing.T) {
+	tplYAML := `#@ load("@ytt:template", "template")
+#@ load("@ytt:library", "library")
+#@ load("@ytt:data", "data")
+values: #@ data.values
+--- #@ template.replace(library.get("lib1").eval())
+`
+	dataValuesYAML := `#@data/values
+---
+name: app
+hosts:
+- a.com
+db:
+  user: admin
+  port: 5432
+`
+	libValuesYAML := `#@data/values
+---
+lib_name: lib
+`
+	libTplYAML := `#@ load("@ytt:data", "data")
+---
+lib_values: #@ data.values
+`
+
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("tpl.yml", []byte(tplYAML))),
+		files.MustNewFileFromSource(files.NewBytesSource("values.yml", []byte(dataValuesYAML))),
+		files.MustNewFileFromSource(files.NewBytesSource("_ytt_lib/lib1/values.yml", []byte(libValuesYAML))),
+		files.MustNewFileFromSource(files.NewBytesSource("_ytt_lib/lib1/tpl.yml", []byte(libTplYAML))),
+	})
+
+	dir, err := ioutil.TempDir(os.TempDir(), "ytt-data-values-file")
+	if err != nil {
+		t.Fatalf("Expected creating a temp dir to not fail: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFile := func(name, contents string) string {
+		path := filepath.Join(dir, name)
+		err := ioutil.WriteFile(path, []byte(contents), 0600)
+		if err != nil {
+			t.Fatalf("Expected writing file to not fail: %v", err)
+		}
+		return path
+	}
+
+	// annotations in plain files are just comments, hence are not evaluated
+	plainYAMLPath := writeFile("plain.yml", `#@ fail("not evaluated")
+hosts:
+- b.com
+- c.com
+db:
+  port: 6000
+---
+name: #@ "not evaluated"
+`)
+	plainJSONPath := writeFile("plain.json", `{"db": {"user": "root"}}`)
+	libJSONPath := writeFile("lib.json", `{"lib_name": "from-file"}`)
+
+	t.Run("overlays plain YAML and JSON files without templating them", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.DataValuesFlags.FromFiles = []string{plainYAMLPath, plainJSONPath, "@lib1:" + libJSONPath}
+
+		expectedOutput := `values:
+  name: null
+  hosts:
+  - b.com
+  - c.com
+  db:
+    user: root
+    port: 6000
+---
+lib_values:
+  lib_name: from-file
+`
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+		if out.Err != nil {
+			t.Fatalf("Expected RunWithFiles to succeed, but was error: %s", out.Err)
+		}
+
+		outBytes, err := out.DocSet.AsBytes()
+		if err != nil {
+			t.Fatalf("Expected printing output to succeed, but was error: %s", err)
+		}
+
+		if string(outBytes) != expectedOutput {
+			t.Fatalf("Expected output to include values from files, but was: >>>%s<<< vs >>>%s<<<", outBytes, expectedOutput)
+		}
+	})
+
+	t.Run("fails when document is not a map", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.DataValuesFlags.FromFiles = []string{writeFile("array.yml", "- a\n")}
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+		if out.Err == nil || !strings.Contains(out.Err.Error(), "Expected document in file '"+dir+"/array.yml' to be a map") {
+			t.Fatalf("Expected RunWithFiles to fail, but was: %v", out.Err)
+		}
+	})
+}
+
+func TestDataValuesFromDotenvPropertiesAndTOMLFiles(t *testing.T) {
+	tplYAML := `#@ load("@ytt:template", "template")
+#@ load("@ytt:library", "library")
+#@ load("@ytt:data", "data")
+values: #@ data.values
+--- #@ template.replace(library.get("lib1").eval())
+`
+	schemaYAML := `#@schema/match data_values=True
+---
+name: app
+debug: false
+db:
+  host: localhost
+  port: 5432
+hosts:
+- ""
+`
+	libValuesYAML := `#@data/values
+---
+lib_name: lib
+`
+	libTplYAML := `#@ load("@ytt:data", "data")
+---
+lib_values: #@ data.values
+`
+
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("tpl.yml", []byte(tplYAML))),
+		files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+		files.MustNewFileFromSource(files.NewBytesSource("_ytt_lib/lib1/values.yml", []byte(libValuesYAML))),
+		files.MustNewFileFromSource(files.NewBytesSource("_ytt_lib/lib1/tpl.yml", []byte(libTplYAML))),
+	})
+
+	dir, err := ioutil.TempDir(os.TempDir(), "ytt-data-values-formats")
+	if err != nil {
+		t.Fatalf("Expected creating a temp dir to not fail: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFile := func(name, contents string) string {
+		path := filepath.Join(dir, name)
+		err := ioutil.WriteFile(path, []byte(contents), 0600)
+		if err != nil {
+			t.Fatalf("Expected writing file to not fail: %v", err)
+		}
+		return path
+	}
+
+	tomlPath := writeFile("values.toml", `name = "from-toml"
+hosts = ["a.com", "b.com"]
+
+[db]
+host = "db.local"
+port = 3306
+`)
+	dotenvPath := writeFile("values.env", `# comment
+export debug=true
+db__host="db.\"quoted\""  
+name='from-dotenv' # comment
+`)
+	propertiesPath := writeFile("values.properties", `! comment
+db__port = 7000
+name: from-\
+  properties
+`)
+	libDotenvPath := writeFile("lib.env", "lib_name=from-lib-dotenv\n")
+
+	runAndCompare := func(t *testing.T, opts *cmdtpl.Options, expectedOutput string) {
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+		if out.Err != nil {
+			t.Fatalf("Expected RunWithFiles to succeed, but was error: %s", out.Err)
+		}
+
+		outBytes, err := out.DocSet.AsBytes()
+		if err != nil {
+			t.Fatalf("Expected printing output to succeed, but was error: %s", err)
+		}
+
+		if string(outBytes) != expectedOutput {
+			t.Fatalf("Expected output to include values from files, but was: >>>%s<<< vs >>>%s<<<", outBytes, expectedOutput)
+		}
+	}
+
+	t.Run("overlays TOML, then dotenv, then properties files", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+		opts.DataValuesFlags.FromTOMLFiles = []string{tomlPath}
+		opts.DataValuesFlags.FromDotenvFiles = []string{dotenvPath, "@lib1:" + libDotenvPath}
+		opts.DataValuesFlags.FromPropertiesFiles = []string{propertiesPath}
+
+		runAndCompare(t, opts, `values:
+  name: from-properties
+  debug: true
+  db:
+    host: db."quoted"
+    port: 7000
+  hosts:
+  - a.com
+  - b.com
+---
+lib_values:
+  lib_name: from-lib-dotenv
+`)
+	})
+
+	t.Run("splits keys by configured separator", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+		opts.DataValuesFlags.FromPropertiesFiles = []string{writeFile("dotted.properties", "db.port=1234\n")}
+		opts.DataValuesFlags.FileKeySep = "."
+
+		runAndCompare(t, opts, `values:
+  name: app
+  debug: false
+  db:
+    host: localhost
+    port: 1234
+  hosts: []
+---
+lib_values:
+  lib_name: lib
+`)
+	})
+
+	t.Run("reads dotenv quoted values up to the first unescaped quote", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+		opts.DataValuesFlags.FromDotenvFiles = []string{writeFile("quoted.env", `name="bar" # say "hi"
+db__host="\$HOST \"x\" \\ \d" # it's
+`)}
+
+		runAndCompare(t, opts, `values:
+  name: bar
+  debug: false
+  db:
+    host: $HOST "x" \ \d
+    port: 5432
+  hosts: []
+---
+lib_values:
+  lib_name: lib
+`)
+	})
+
+	t.Run("fails on dotenv quoted values followed by other than a comment", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+		opts.DataValuesFlags.FromDotenvFiles = []string{writeFile("trailing.env", `name="bar" baz`+"\n")}
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+		if out.Err == nil || !strings.Contains(out.Err.Error(), "Parsing value on line 1: Expected only a comment after quoted value, but found 'baz'") {
+			t.Fatalf("Expected RunWithFiles to fail, but was: %v", out.Err)
+		}
+	})
+
+	t.Run("reports line of value that does not match schema", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+		opts.DataValuesFlags.FromDotenvFiles = []string{writeFile("invalid.env", "\n\ndb__port=abc\n")}
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+		if out.Err == nil || !strings.Contains(out.Err.Error(), "key 'db.port' (dotenv file '"+dir+"/invalid.env:3')") {
+			t.Fatalf("Expected RunWithFiles to fail, but was: %v", out.Err)
+		}
+	})
+
+	t.Run("fails on invalid TOML", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+		opts.DataValuesFlags.FromTOMLFiles = []string{writeFile("invalid.toml", "name = \n")}
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+		if out.Err == nil || !strings.Contains(out.Err.Error(), "Extracting data values from TOML file: Parsing file '"+dir+"/invalid.toml'") {
+			t.Fatalf("Expected RunWithFiles to fail, but was: %v", out.Err)
+		}
+	})
+}
+
+func TestDataValuesArrayMergeStrategies(t *testing.T) {
+	tplYAML := `#@ load("@ytt:data", "data")
+values: #@ data.values
+`
+	schemaYAML := `#@schema/match data_values=True
+---
+hosts:
+- ""
+#@schema/merge "merge-by-key:name"
+users:
+- name: ""
+  role: viewer
+`
+	dataValuesYAML := `#@data/values
+---
+hosts:
+- a.com
+users:
+- name: alice
+- name: bob
+  role: admin
+`
+	secondDataValuesYAML := `#@data/values
+---
+hosts:
+- b.com
+users:
+- name: bob
+  role: editor
+- name: carol
+`
+
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("tpl.yml", []byte(tplYAML))),
+		files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+		files.MustNewFileFromSource(files.NewBytesSource("values.yml", []byte(dataValuesYAML))),
+		files.MustNewFileFromSource(files.NewBytesSource("values2.yml", []byte(secondDataValuesYAML))),
+	})
+
+	dir, err := ioutil.TempDir(os.TempDir(), "ytt-data-values-merge")
+	if err != nil {
+		t.Fatalf("Expected creating a temp dir to not fail: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	plainPath := filepath.Join(dir, "plain.yml")
+	err = ioutil.WriteFile(plainPath, []byte("hosts:\n- c.com\nusers:\n- name: alice\n  role: owner\n"), 0600)
+	if err != nil {
+		t.Fatalf("Expected writing file to not fail: %v", err)
+	}
+
+	runAndCompare := func(t *testing.T, opts *cmdtpl.Options, expectedOutput string) {
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+		if out.Err != nil {
+			t.Fatalf("Expected RunWithFiles to succeed, but was error: %s", out.Err)
+		}
+
+		outBytes, err := out.DocSet.AsBytes()
+		if err != nil {
+			t.Fatalf("Expected printing output to succeed, but was error: %s", err)
+		}
+
+		if string(outBytes) != expectedOutput {
+			t.Fatalf("Expected output to be merged, but was: >>>%s<<< vs >>>%s<<<", outBytes, expectedOutput)
+		}
+	}
+
+	t.Run("uses schema strategy and default strategy of each source", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+		opts.DataValuesFlags.FromFiles = []string{plainPath}
+
+		runAndCompare(t, opts, `values:
+  hosts:
+  - c.com
+  users:
+  - name: alice
+    role: owner
+  - name: bob
+    role: editor
+  - name: carol
+    role: viewer
+`)
+	})
+
+	t.Run("uses command line strategy unless schema specifies otherwise", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+		opts.DataValuesFlags.FromFiles = []string{plainPath}
+		opts.DataValuesFlags.ArrayMerge = "append"
+
+		runAndCompare(t, opts, `values:
+  hosts:
+  - a.com
+  - b.com
+  - c.com
+  users:
+  - name: alice
+    role: owner
+  - name: bob
+    role: editor
+  - name: carol
+    role: viewer
+`)
+	})
+
+	t.Run("uses command line strategy for arrays of templated files", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+		opts.DataValuesFlags.ArrayMerge = "replace"
+
+		runAndCompare(t, opts, `values:
+  hosts:
+  - b.com
+  users:
+  - name: alice
+    role: viewer
+  - name: bob
+    role: editor
+  - name: carol
+    role: viewer
+`)
+	})
+
+	t.Run("fails on unknown command line strategy", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.DataValuesFlags.ArrayMerge = "merge-by-key:"
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+		if out.Err == nil || !strings.Contains(out.Err.Error(), "Flag --data-values-array-merge: Expected array merge strategy 'merge-by-key:' to specify field name") {
+			t.Fatalf("Expected RunWithFiles to fail, but was: %v", out.Err)
+		}
+	})
+
+	t.Run("fails when schema strategy is not on an array", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+
+		invalidSchemaYAML := `#@schema/match data_values=True
+---
+#@schema/merge "append"
+name: ""
+`
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("tpl.yml", []byte(tplYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(invalidSchemaYAML))),
+		})
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+		if out.Err == nil || !strings.Contains(out.Err.Error(), "expected @schema/merge annotation to be on an array, but found string") {
+			t.Fatalf("Expected RunWithFiles to fail, but was: %v", out.Err)
+		}
+	})
+}
+
+func TestDataValuesUnsetAndDefault(t *testing.T) {
+	tplYAML := `#@ load("@ytt:data", "data")
+values: #@ data.values
+`
+	schemaYAML := `#@schema/match data_values=True
+---
+app:
+  port: 8080
+  debug: false
+  #@schema/nullable
+  host: ""
+`
+	dataValuesYAML := `#@data/values
+---
+app:
+  port: 9090
+  debug: true
+`
+	schemaFilesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("tpl.yml", []byte(tplYAML))),
+		files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+		files.MustNewFileFromSource(files.NewBytesSource("values.yml", []byte(dataValuesYAML))),
+	})
+	plainFilesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("tpl.yml", []byte(tplYAML))),
+		files.MustNewFileFromSource(files.NewBytesSource("values.yml", []byte(dataValuesYAML))),
+	})
+
+	runAndCompare := func(t *testing.T, opts *cmdtpl.Options, filesToProcess []*files.File, expectedOutput string) {
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+		if out.Err != nil {
+			t.Fatalf("Expected RunWithFiles to succeed, but was error: %s", out.Err)
+		}
+
+		outBytes, err := out.DocSet.AsBytes()
+		if err != nil {
+			t.Fatalf("Expected printing output to succeed, but was error: %s", err)
+		}
+
+		if string(outBytes) != expectedOutput {
+			t.Fatalf("Expected output to match, but was: >>>%s<<< vs >>>%s<<<", outBytes, expectedOutput)
+		}
+	}
+
+	t.Run("resets values to schema defaults", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+		opts.DataValuesFlags.KVsFromStrings = []string{"app.host=example.com"}
+		opts.DataValuesFlags.KVsToDefault = []string{"app.port", "app.host"}
+
+		runAndCompare(t, opts, schemaFilesToProcess, `values:
+  app:
+    port: 8080
+    debug: true
+    host: null
+`)
+	})
+
+	t.Run("fails to reset values to defaults without schema", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.DataValuesFlags.KVsToDefault = []string{"app.port"}
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: plainFilesToProcess}, ui.NewTTY(false))
+		if out.Err == nil || !strings.Contains(out.Err.Error(), "Resetting data value (key 'app.port' (default arg):1) to its default: "+
+			"Expected schema to provide default, but schema is not present") {
+			t.Fatalf("Expected RunWithFiles to fail, but was: %v", out.Err)
+		}
+	})
+
+	t.Run("sets unset values to null when schema is present", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+		opts.DataValuesFlags.KVsFromStrings = []string{"app.host=example.com"}
+		opts.DataValuesFlags.KVsToUnset = []string{"app.host"}
+
+		runAndCompare(t, opts, schemaFilesToProcess, `values:
+  app:
+    port: 9090
+    debug: true
+    host: null
+`)
+	})
+
+	t.Run("fails to unset values that are not nullable when schema is present", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+		opts.DataValuesFlags.KVsToUnset = []string{"app.debug"}
+
+		expectedErr := `key 'app.debug' (unset arg):1 |
+                              |
+                              | TYPE MISMATCH - the value of this item is not what schema expected:
+                              |      found: null
+                              |   expected: boolean (by schema.yml:5)
+`
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: schemaFilesToProcess}, ui.NewTTY(false))
+		if out.Err == nil || !strings.Contains(out.Err.Error(), expectedErr) {
+			t.Fatalf("Expected RunWithFiles to fail, but was: %v", out.Err)
+		}
+	})
+
+	t.Run("removes values without schema, including values set via flags", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.DataValuesFlags.KVsFromStrings = []string{"app.debug=false"}
+		opts.DataValuesFlags.KVsToUnset = []string{"app.debug", "app.missing+"}
+
+		runAndCompare(t, opts, plainFilesToProcess, `values:
+  app:
+    port: 9090
+`)
+	})
+
+	t.Run("fails when value to remove is missing", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.DataValuesFlags.KVsToUnset = []string{"app.missing"}
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: plainFilesToProcess}, ui.NewTTY(false))
+		if out.Err == nil || !strings.Contains(out.Err.Error(), "Expected number of matched nodes to be 1, but was 0") {
+			t.Fatalf("Expected RunWithFiles to fail, but was: %v", out.Err)
+		}
+	})
+
+	t.Run("applies unset and default to values given before them on command line", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+
+		cmd := &cobra.Command{}
+		opts.DataValuesFlags.Set(cmd)
+
+		err := cmd.ParseFlags([]string{"--data-value-default", "app.port", "-v", "app.port=7070",
+			"-v", "app.host=example.com", "--data-value-unset", "app.host", "--data-value-yaml", "app.host=other.com",
+			"--data-value-yaml", "app.debug=false", "--data-value-default", "app.debug"})
+		if err != nil {
+			t.Fatalf("Expected parsing flags to succeed, but was error: %s", err)
+		}
+
+		runAndCompare(t, opts, schemaFilesToProcess, `values:
+  app:
+    port: 7070
+    debug: false
+    host: other.com
+`)
+	})
+
+	t.Run("keeps precedence of values set via different flags regardless of their order", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+
+		cmd := &cobra.Command{}
+		opts.DataValuesFlags.Set(cmd)
+
+		err := cmd.ParseFlags([]string{"--data-value-yaml", "app.port=8080", "-v", "app.port=7070",
+			"--data-value-yaml", "app.debug=false", "--data-value-unset", "app.debug", "-v", "app.debug=yes"})
+		if err != nil {
+			t.Fatalf("Expected parsing flags to succeed, but was error: %s", err)
+		}
+
+		runAndCompare(t, opts, plainFilesToProcess, `values:
+  app:
+    port: 8080
+`)
+	})
+}
@@ -907,3 +907,44 @@ bar:
 		}
 	})
 }
+
+func TestAllowClock(t *testing.T) {
+	tpl := []byte(`
+#@ load("@ytt:time", "time")
+#@ now = time.now()
+recent: #@ now > time.parse_time("2021-01-01T00:00:00Z")
+expires: #@ now + time.parse_duration("24h")
+`)
+
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("template.yml", tpl)),
+	})
+
+	t.Run("fails to access current time by default", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+		if out.Err == nil || !strings.Contains(out.Err.Error(), "access to current time is not allowed") {
+			t.Fatalf("Expected RunWithFiles to fail, but was: %v", out.Err)
+		}
+	})
+
+	t.Run("provides current time when allowed", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.AllowClock = true
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+		if out.Err != nil {
+			t.Fatalf("Expected RunWithFiles to succeed, but was error: %s", out.Err)
+		}
+
+		outBytes, err := out.DocSet.AsBytes()
+		if err != nil {
+			t.Fatalf("Expected printing output to succeed, but was error: %s", err)
+		}
+
+		if !strings.HasPrefix(string(outBytes), "recent: true\nexpires: \"") {
+			t.Fatalf("Expected output to include current time, but was: >>>%s<<<", outBytes)
+		}
+	})
+}
@@ -0,0 +1,234 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package template
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type keyValue struct {
+	Key   string
+	Value string
+	Line  int
+}
+
+// parseDotenv parses .env files (KEY=value per line; values may be quoted;
+// lines may start with 'export'; lines starting with '#' are comments)
+func parseDotenv(contents []byte) ([]keyValue, error) {
+	var result []keyValue
+
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	lineNum := 0
+
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+
+		pieces := strings.SplitN(line, "=", 2)
+		if len(pieces) != 2 {
+			return nil, fmt.Errorf("Expected line %d to be key-value pair (format: key=value)", lineNum)
+		}
+
+		key := strings.TrimSpace(pieces[0])
+		if len(key) == 0 {
+			return nil, fmt.Errorf("Expected line %d to have non-empty key", lineNum)
+		}
+
+		val, err := parseDotenvValue(strings.TrimSpace(pieces[1]))
+		if err != nil {
+			return nil, fmt.Errorf("Parsing value on line %d: %s", lineNum, err)
+		}
+
+		result = append(result, keyValue{key, val, lineNum})
+	}
+
+	return result, scanner.Err()
+}
+
+func parseDotenvValue(val string) (string, error) {
+	switch {
+	case strings.HasPrefix(val, `"`):
+		unquoted, rest, err := unquoteDotenvDouble(val[1:])
+		if err != nil {
+			return "", err
+		}
+		return unquoted, checkDotenvRest(rest)
+
+	case strings.HasPrefix(val, "'"):
+		end := strings.Index(val[1:], "'")
+		if end < 0 {
+			return "", fmt.Errorf("Expected closing single quote")
+		}
+		// single quoted values are taken literally
+		return val[1 : end+1], checkDotenvRest(val[end+2:])
+
+	default:
+		// unquoted values may be followed by a comment
+		if idx := strings.Index(val, " #"); idx >= 0 {
+			val = val[:idx]
+		}
+		return strings.TrimSpace(val), nil
+	}
+}
+
+// unquoteDotenvDouble reads double quoted value up to the first unescaped
+// double quote, returning the value and whatever follows the closing quote.
+// Backslash escapes \n, \r, \t, \", \\, \$ and \` are recognized;
+// backslash followed by any other character is kept as is (as in shells).
+func unquoteDotenvDouble(val string) (string, string, error) {
+	var result strings.Builder
+
+	for i := 0; i < len(val); i++ {
+		switch val[i] {
+		case '"':
+			return result.String(), val[i+1:], nil
+
+		case '\\':
+			if i+1 == len(val) {
+				result.WriteByte(val[i])
+				continue
+			}
+			i++
+			switch val[i] {
+			case 'n':
+				result.WriteByte('\n')
+			case 'r':
+				result.WriteByte('\r')
+			case 't':
+				result.WriteByte('\t')
+			case '"', '\\', '$', '`':
+				result.WriteByte(val[i])
+			default:
+				result.WriteByte('\\')
+				result.WriteByte(val[i])
+			}
+
+		default:
+			result.WriteByte(val[i])
+		}
+	}
+
+	return "", "", fmt.Errorf("Expected closing double quote")
+}
+
+// checkDotenvRest allows only whitespace or a comment after a quoted value
+func checkDotenvRest(rest string) error {
+	rest = strings.TrimSpace(rest)
+	if len(rest) > 0 && !strings.HasPrefix(rest, "#") {
+		return fmt.Errorf("Expected only a comment after quoted value, but found '%s'", rest)
+	}
+	return nil
+}
+
+// parseProperties parses Java .properties files (key=value, key: value or 'key value' per
+// logical line; lines ending with '\' continue on the next line; '#' and '!' start comments)
+func parseProperties(contents []byte) ([]keyValue, error) {
+	var result []keyValue
+
+	lines := strings.Split(strings.ReplaceAll(string(contents), "\r\n", "\n"), "\n")
+
+	for i := 0; i < len(lines); i++ {
+		lineNum := i + 1
+		line := strings.TrimLeft(lines[i], " \t\f")
+
+		if len(line) == 0 || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+
+		for isContinued(line) && i+1 < len(lines) {
+			i++
+			line = line[:len(line)-1] + strings.TrimLeft(lines[i], " \t\f")
+		}
+
+		key, val := splitPropertiesLine(line)
+
+		unescapedKey, err := unescapeProperties(key)
+		if err != nil {
+			return nil, fmt.Errorf("Parsing key on line %d: %s", lineNum, err)
+		}
+
+		unescapedVal, err := unescapeProperties(val)
+		if err != nil {
+			return nil, fmt.Errorf("Parsing value on line %d: %s", lineNum, err)
+		}
+
+		result = append(result, keyValue{unescapedKey, unescapedVal, lineNum})
+	}
+
+	return result, nil
+}
+
+// isContinued indicates whether line ends with an odd number of backslashes
+func isContinued(line string) bool {
+	count := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		count++
+	}
+	return count%2 == 1
+}
+
+func splitPropertiesLine(line string) (string, string) {
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++ // skip escaped character
+		case '=', ':':
+			return line[:i], strings.TrimLeft(line[i+1:], " \t\f")
+		case ' ', '\t', '\f':
+			rest := strings.TrimLeft(line[i:], " \t\f")
+			if len(rest) > 0 && (rest[0] == '=' || rest[0] == ':') {
+				rest = rest[1:]
+			}
+			return line[:i], strings.TrimLeft(rest, " \t\f")
+		}
+	}
+	return line, ""
+}
+
+func unescapeProperties(str string) (string, error) {
+	var result strings.Builder
+
+	for i := 0; i < len(str); i++ {
+		if str[i] != '\\' || i+1 == len(str) {
+			result.WriteByte(str[i])
+			continue
+		}
+
+		i++
+		switch str[i] {
+		case 't':
+			result.WriteByte('\t')
+		case 'n':
+			result.WriteByte('\n')
+		case 'r':
+			result.WriteByte('\r')
+		case 'f':
+			result.WriteByte('\f')
+		case 'u':
+			if i+5 > len(str) {
+				return "", fmt.Errorf("Expected four hex digits after '\\u'")
+			}
+			code, err := strconv.ParseUint(str[i+1:i+5], 16, 32)
+			if err != nil {
+				return "", fmt.Errorf("Expected four hex digits after '\\u': %s", err)
+			}
+			result.WriteRune(rune(code))
+			i += 4
+		default:
+			result.WriteByte(str[i])
+		}
+	}
+
+	return result.String(), nil
+}
@@ -6,24 +6,38 @@ package template
 import (
 	"fmt"
 	"io/ioutil"
+	"math"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/k14s/starlark-go/starlark"
 	"github.com/k14s/ytt/pkg/filepos"
+	"github.com/k14s/ytt/pkg/schema"
+	"github.com/k14s/ytt/pkg/structmeta"
 	"github.com/k14s/ytt/pkg/template"
 	"github.com/k14s/ytt/pkg/workspace"
 	"github.com/k14s/ytt/pkg/yamlmeta"
+	"github.com/k14s/ytt/pkg/yttlibrary"
 	yttoverlay "github.com/k14s/ytt/pkg/yttlibrary/overlay"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 const (
-	dvsKVSep     = "="
-	dvsMapKeySep = "."
+	dvsKVSep         = "="
+	dvsMapKeySep     = "."
+	dvsFileKeySepDef = "__"
 )
 
 type DataValuesFlags struct {
+	FromFiles           []string
+	FromDotenvFiles     []string
+	FromPropertiesFiles []string
+	FromTOMLFiles       []string
+	FileKeySep          string
+	ArrayMerge          string
+
 	EnvFromStrings []string
 	EnvFromYAML    []string
 
@@ -31,29 +45,56 @@ type DataValuesFlags struct {
 	KVsFromYAML    []string
 	KVsFromFiles   []string
 
-	Inspect bool
+	KVsToUnset   []string
+	KVsToDefault []string
+
+	Inspect           bool
+	InspectProvenance bool
+	InspectLibrary    string
 
 	EnvironFunc func() []string
+
+	// positions of KVsToUnset and KVsToDefault on command line (if known)
+	unsetPositions   []kvOpPosition
+	defaultPositions []kvOpPosition
 }
 
 func (s *DataValuesFlags) Set(cmd *cobra.Command) {
-	cmd.Flags().StringArrayVar(&s.EnvFromStrings, "data-values-env", nil, "Extract data values (as strings) from prefixed env vars (format: PREFIX for PREFIX_all__key1=str) (can be specified multiple times)")
+	cmd.Flags().StringArrayVar(&s.FromFiles, "data-values-file", nil, "Set multiple data values via plain YAML (or JSON) files, not templated and without annotations; arrays replace previous values (format: /file/path.yml, @lib1:/file/path.yml) (can be specified multiple times)")
+	cmd.Flags().StringArrayVar(&s.FromTOMLFiles, "data-values-toml", nil, "Set multiple data values via TOML files; arrays replace previous values (format: /file/path.toml, @lib1:/file/path.toml) (can be specified multiple times)")
+	cmd.Flags().StringArrayVar(&s.FromDotenvFiles, "data-values-dotenv", nil, "Set data values (as strings, converted to types expected by schema) via .env files (format: /file/path.env, @lib1:/file/path.env for all__key1=str) (can be specified multiple times)")
+	cmd.Flags().StringArrayVar(&s.FromPropertiesFiles, "data-values-properties", nil, "Set data values (as strings, converted to types expected by schema) via Java .properties files (format: /file/path.properties, @lib1:/file/path.properties for all__key1=str) (can be specified multiple times)")
+	cmd.Flags().StringVar(&s.ArrayMerge, "data-values-array-merge", "", "Combine arrays of data values with previous values using given strategy, unless schema specifies otherwise via @schema/merge (format: replace, append, merge-by-key:name)")
+	cmd.Flags().StringVar(&s.FileKeySep, "data-values-key-sep", dvsFileKeySepDef, "Separator of nested keys in .env and .properties files (e.g. '.' for all.key1=str)")
+
+	cmd.Flags().StringArrayVar(&s.EnvFromStrings, "data-values-env", nil, "Extract data values (as strings, converted to types expected by schema) from prefixed env vars (format: PREFIX for PREFIX_all__key1=str) (can be specified multiple times)")
 	cmd.Flags().StringArrayVar(&s.EnvFromYAML, "data-values-env-yaml", nil, "Extract data values (parsed as YAML) from prefixed env vars (format: PREFIX for PREFIX_all__key1=true) (can be specified multiple times)")
 
-	cmd.Flags().StringArrayVarP(&s.KVsFromStrings, "data-value", "v", nil, "Set specific data value to given value, as string (format: all.key1.subkey=123) (can be specified multiple times)")
+	cmd.Flags().StringArrayVarP(&s.KVsFromStrings, "data-value", "v", nil, "Set specific data value to given value, as string (converted to type expected by schema) (format: all.key1.subkey=123) (can be specified multiple times)")
 	cmd.Flags().StringArrayVar(&s.KVsFromYAML, "data-value-yaml", nil, "Set specific data value to given value, parsed as YAML (format: all.key1.subkey=true) (can be specified multiple times)")
 	cmd.Flags().StringArrayVar(&s.KVsFromFiles, "data-value-file", nil, "Set specific data value to given file contents, as string (format: all.key1.subkey=/file/path) (can be specified multiple times)")
+	cmd.Flags().Var(s.kvOpFlag(&s.KVsToUnset, &s.unsetPositions), "data-value-unset", "Remove specific data value given before this flag; when schema is present, value is set to null instead (hence it has to be nullable) (format: all.key1.subkey, all.key1.subkey+ if it may be missing) (can be specified multiple times)")
+	cmd.Flags().Var(s.kvOpFlag(&s.KVsToDefault, &s.defaultPositions), "data-value-default", "Reset specific data value given before this flag to its default as given by schema (requires schema) (format: all.key1.subkey, all.key1.subkey+ if it may be missing) (can be specified multiple times)")
 
 	cmd.Flags().BoolVar(&s.Inspect, "data-values-inspect", false, "Inspect data values")
+	cmd.Flags().BoolVar(&s.InspectProvenance, "provenance", false, "Annotate each inspected data value with sources that set it, in order (schema defaults, files, env vars, flags) (requires --data-values-inspect or --data-values-inspect-library)")
+	cmd.Flags().StringVar(&s.InspectLibrary, "data-values-inspect-library", "", "Inspect data values of a library as it was evaluated, i.e. including with_data_values and @library/ref values (format: @lib1, @~alias, @lib1@nested)")
 }
 
 type dataValuesFlagsSource struct {
 	Values        []string
 	TransformFunc valueTransformFunc
+	FromStrings   bool
 }
 
 type valueTransformFunc func(string) (interface{}, error)
 
+type dataValuesKVFileSource struct {
+	Paths     []string
+	Format    string
+	ParseFunc func([]byte) ([]keyValue, error)
+}
+
 func (s *DataValuesFlags) AsOverlays(strict bool) ([]*workspace.DataValues, []*workspace.DataValues, error) {
 	plainValFunc := func(rawVal string) (interface{}, error) { return rawVal, nil }
 
@@ -67,46 +108,159 @@ func (s *DataValuesFlags) AsOverlays(strict bool) ([]*workspace.DataValues, []*w
 
 	var result []*workspace.DataValues
 
-	for _, src := range []dataValuesFlagsSource{{s.EnvFromStrings, plainValFunc}, {s.EnvFromYAML, yamlValFunc}} {
+	for _, path := range s.FromFiles {
+		vals, err := s.dataValuesFile(path, strict)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Extracting data values from file: %s", err)
+		}
+		result = append(result, vals...)
+	}
+
+	for _, path := range s.FromTOMLFiles {
+		val, err := s.tomlFile(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Extracting data values from TOML file: %s", err)
+		}
+		result = append(result, val)
+	}
+
+	for _, src := range []dataValuesKVFileSource{{s.FromDotenvFiles, "dotenv", parseDotenv}, {s.FromPropertiesFiles, "properties", parseProperties}} {
+		for _, path := range src.Paths {
+			vals, err := s.kvFile(path, src)
+			if err != nil {
+				return nil, nil, fmt.Errorf("Extracting data values from %s file: %s", src.Format, err)
+			}
+			result = append(result, vals...)
+		}
+	}
+
+	// env variables take precedence over data values files
+	for _, src := range []dataValuesFlagsSource{{s.EnvFromStrings, plainValFunc, true}, {s.EnvFromYAML, yamlValFunc, false}} {
 		for _, envPrefix := range src.Values {
 			vals, err := s.env(envPrefix, src.TransformFunc)
 			if err != nil {
 				return nil, nil, fmt.Errorf("Extracting data values from env under prefix '%s': %s", envPrefix, err)
 			}
+			for _, val := range vals {
+				val.FromStrings = src.FromStrings
+			}
 			result = append(result, vals...)
 		}
 	}
 
 	// KVs and files take precedence over environment variables
-	for _, src := range []dataValuesFlagsSource{{s.KVsFromStrings, plainValFunc}, {s.KVsFromYAML, yamlValFunc}} {
+	kvVals, err := s.kvOverlays(plainValFunc, yamlValFunc)
+	if err != nil {
+		return nil, nil, err
+	}
+	result = append(result, kvVals...)
+
+	var overlayValues []*workspace.DataValues
+	var libraryOverlays []*workspace.DataValues
+	for _, doc := range result {
+		if doc.HasLib() {
+			libraryOverlays = append(libraryOverlays, doc)
+		} else {
+			overlayValues = append(overlayValues, doc)
+		}
+	}
+
+	return overlayValues, libraryOverlays, nil
+}
+
+// kvOverlays returns values of KV flags: values set via -v, then --data-value-yaml,
+// then --data-value-file, with each unset and default applied right after values given
+// before it on command line (or after all of them if its position is not known)
+func (s *DataValuesFlags) kvOverlays(plainValFunc, yamlValFunc valueTransformFunc) ([]*workspace.DataValues, error) {
+	var setVals []*workspace.DataValues
+
+	for _, src := range []dataValuesFlagsSource{{s.KVsFromStrings, plainValFunc, true}, {s.KVsFromYAML, yamlValFunc, false}} {
 		for _, kv := range src.Values {
 			val, err := s.kv(kv, src.TransformFunc)
 			if err != nil {
-				return nil, nil, fmt.Errorf("Extracting data value from KV: %s", err)
+				return nil, fmt.Errorf("Extracting data value from KV: %s", err)
 			}
-			result = append(result, val)
+			val.FromStrings = src.FromStrings
+			setVals = append(setVals, val)
 		}
 	}
 
 	for _, file := range s.KVsFromFiles {
 		val, err := s.file(file)
 		if err != nil {
-			return nil, nil, fmt.Errorf("Extracting data value from file: %s", err)
+			return nil, fmt.Errorf("Extracting data value from file: %s", err)
 		}
-		result = append(result, val)
+		setVals = append(setVals, val)
 	}
 
-	var overlayValues []*workspace.DataValues
-	var libraryOverlays []*workspace.DataValues
-	for _, doc := range result {
-		if doc.HasLib() {
-			libraryOverlays = append(libraryOverlays, doc)
-		} else {
-			overlayValues = append(overlayValues, doc)
+	type kvOp struct {
+		val      *workspace.DataValues
+		position kvOpPosition
+	}
+	var ops []kvOp
+
+	for i, key := range s.KVsToUnset {
+		val, err := s.unset(key)
+		if err != nil {
+			return nil, fmt.Errorf("Extracting data value to unset: %s", err)
 		}
+		ops = append(ops, kvOp{val, s.opPosition(s.unsetPositions, i)})
 	}
 
-	return overlayValues, libraryOverlays, nil
+	for i, key := range s.KVsToDefault {
+		val, err := s.resetToDefault(key)
+		if err != nil {
+			return nil, fmt.Errorf("Extracting data value to reset to default: %s", err)
+		}
+		ops = append(ops, kvOp{val, s.opPosition(s.defaultPositions, i)})
+	}
+
+	sort.SliceStable(ops, func(i, j int) bool {
+		iAfter, jAfter := s.appliedAfter(ops[i].position), s.appliedAfter(ops[j].position)
+		if iAfter != jAfter {
+			return iAfter < jAfter
+		}
+		return ops[i].position.Seq < ops[j].position.Seq
+	})
+
+	var result []*workspace.DataValues
+	for i := 0; i <= len(setVals); i++ {
+		for len(ops) > 0 && s.appliedAfter(ops[0].position) == i {
+			result = append(result, ops[0].val)
+			ops = ops[1:]
+		}
+		if i < len(setVals) {
+			result = append(result, setVals[i])
+		}
+	}
+	return result, nil
+}
+
+// kvOpPosition records number of values given via -v, --data-value-yaml and --data-value-file
+// before a --data-value-unset or --data-value-default flag, along with its sequence among them
+type kvOpPosition struct {
+	Strings, YAML, Files int
+	Seq                  int
+}
+
+func (s *DataValuesFlags) opPosition(positions []kvOpPosition, i int) kvOpPosition {
+	if i < len(positions) {
+		return positions[i]
+	}
+	// values that were not given on command line are applied after all others
+	return kvOpPosition{len(s.KVsFromStrings), len(s.KVsFromYAML), len(s.KVsFromFiles), math.MaxInt32}
+}
+
+// appliedAfter returns number of set values (in order of precedence) to be applied before an operation
+func (s *DataValuesFlags) appliedAfter(pos kvOpPosition) int {
+	switch {
+	case pos.Files > 0:
+		return len(s.KVsFromStrings) + len(s.KVsFromYAML) + pos.Files
+	case pos.YAML > 0:
+		return len(s.KVsFromStrings) + pos.YAML
+	default:
+		return pos.Strings
+	}
 }
 
 func (s *DataValuesFlags) env(prefix string, valueFunc valueTransformFunc) ([]*workspace.DataValues, error) {
@@ -144,7 +298,7 @@ func (s *DataValuesFlags) env(prefix string, valueFunc valueTransformFunc) ([]*w
 
 		// '__' gets translated into a '.' since periods may not be liked by shells
 		keyPieces := strings.Split(strings.TrimPrefix(pieces[0], keyPrefix+envKeyPrefix), envMapKeySep)
-		overlay := s.buildOverlay(keyPieces, val, "env var")
+		overlay := s.buildOverlay(keyPieces, val, yttoverlay.AnnotationReplace, fmt.Sprintf("env var '%s'", pieces[0]))
 
 		dvs, err := workspace.NewDataValuesWithOptionalLib(overlay, libRef)
 		if err != nil {
@@ -173,7 +327,44 @@ func (s *DataValuesFlags) kv(kv string, valueFunc valueTransformFunc) (*workspac
 		return nil, err
 	}
 
-	overlay := s.buildOverlay(strings.Split(key, dvsMapKeySep), val, "kv arg")
+	overlay := s.buildOverlay(strings.Split(key, dvsMapKeySep), val, yttoverlay.AnnotationReplace, "kv arg")
+
+	return workspace.NewDataValuesWithOptionalLib(overlay, libRef)
+}
+
+// unset builds an overlay that removes value at given key
+// (schema, if present, turns removal into setting value to null)
+func (s *DataValuesFlags) unset(key string) (*workspace.DataValues, error) {
+	dvs, err := s.keyOnlyOverlay(key, yttoverlay.AnnotationRemove, "unset arg")
+	if err != nil {
+		return nil, err
+	}
+	dvs.Unset = true
+	return dvs, nil
+}
+
+// resetToDefault builds an overlay that replaces value at given key with its default
+// (filled in once schema is known; without schema there is no default to reset to)
+func (s *DataValuesFlags) resetToDefault(key string) (*workspace.DataValues, error) {
+	dvs, err := s.keyOnlyOverlay(key, yttoverlay.AnnotationReplace, "default arg")
+	if err != nil {
+		return nil, err
+	}
+	dvs.ResetToDefaults = true
+	return dvs, nil
+}
+
+func (s *DataValuesFlags) keyOnlyOverlay(key string, op structmeta.AnnotationName, desc string) (*workspace.DataValues, error) {
+	libRef, key, err := s.libraryRefAndKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(key) == 0 {
+		return nil, fmt.Errorf("Expected key to not be empty")
+	}
+
+	overlay := s.buildOverlay(strings.Split(key, dvsMapKeySep), nil, op, desc)
 
 	return workspace.NewDataValuesWithOptionalLib(overlay, libRef)
 }
@@ -202,11 +393,140 @@ func (s *DataValuesFlags) file(kv string) (*workspace.DataValues, error) {
 		return nil, err
 	}
 
-	overlay := s.buildOverlay(strings.Split(key, dvsMapKeySep), string(contents), "key=file arg")
+	overlay := s.buildOverlay(strings.Split(key, dvsMapKeySep), string(contents), yttoverlay.AnnotationReplace, "key=file arg")
 
 	return workspace.NewDataValuesWithOptionalLib(overlay, libRef)
 }
 
+func (s *DataValuesFlags) dataValuesFile(path string, strict bool) ([]*workspace.DataValues, error) {
+	libRef, path, err := s.libraryRefAndKey(path)
+	if err != nil {
+		return nil, err
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Reading file '%s'", path)
+	}
+
+	docSet, err := yamlmeta.NewParser(yamlmeta.ParserOpts{Strict: strict}).ParseBytes(contents, path)
+	if err != nil {
+		return nil, fmt.Errorf("Parsing file '%s': %s", path, err)
+	}
+
+	var result []*workspace.DataValues
+
+	for _, doc := range docSet.Items {
+		if doc.IsEmpty() {
+			continue
+		}
+		if _, isMap := doc.Value.(*yamlmeta.Map); !isMap {
+			return nil, fmt.Errorf("Expected document in file '%s' to be a map (%s)", path, doc.Position.AsCompactString())
+		}
+
+		dvs, err := workspace.NewDataValuesWithOptionalLib(doc, libRef)
+		if err != nil {
+			return nil, err
+		}
+		// files cannot carry overlay annotations, hence arrays
+		// replace (rather than append to) previous values by default
+		dvs.ArrayMerge = schema.ArrayMergeStrategy{Op: schema.ArrayMergeReplace}
+
+		result = append(result, dvs)
+	}
+
+	return result, nil
+}
+
+func (s *DataValuesFlags) tomlFile(path string) (*workspace.DataValues, error) {
+	libRef, path, err := s.libraryRefAndKey(path)
+	if err != nil {
+		return nil, err
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Reading file '%s'", path)
+	}
+
+	val, err := yttlibrary.DecodeTOML(contents)
+	if err != nil {
+		return nil, fmt.Errorf("Parsing file '%s': %s", path, err)
+	}
+
+	// TOML decoder does not keep track of lines, hence only file is known
+	pos := filepos.NewUnknownPosition()
+	pos.SetFile(path)
+
+	doc := &yamlmeta.Document{Value: yamlmeta.NewASTFromInterface(val), Position: pos}
+	s.setPositions(doc.Value, pos)
+
+	dvs, err := workspace.NewDataValuesWithOptionalLib(doc, libRef)
+	if err != nil {
+		return nil, err
+	}
+	dvs.ArrayMerge = schema.ArrayMergeStrategy{Op: schema.ArrayMergeReplace}
+
+	return dvs, nil
+}
+
+func (s *DataValuesFlags) kvFile(path string, src dataValuesKVFileSource) ([]*workspace.DataValues, error) {
+	libRef, path, err := s.libraryRefAndKey(path)
+	if err != nil {
+		return nil, err
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Reading file '%s'", path)
+	}
+
+	kvs, err := src.ParseFunc(contents)
+	if err != nil {
+		return nil, fmt.Errorf("Parsing file '%s': %s", path, err)
+	}
+
+	keySep := s.FileKeySep
+	if len(keySep) == 0 {
+		keySep = dvsFileKeySepDef
+	}
+
+	var result []*workspace.DataValues
+
+	for _, kv := range kvs {
+		// similar to env variables, separator gets translated into a '.'
+		keyPieces := strings.Split(kv.Key, keySep)
+		overlay := s.buildOverlay(keyPieces, kv.Value, yttoverlay.AnnotationReplace, fmt.Sprintf("%s file '%s:%d'", src.Format, path, kv.Line))
+
+		dvs, err := workspace.NewDataValuesWithOptionalLib(overlay, libRef)
+		if err != nil {
+			return nil, err
+		}
+		dvs.FromStrings = true
+
+		result = append(result, dvs)
+	}
+
+	return result, nil
+}
+
+func (s *DataValuesFlags) setPositions(val interface{}, pos *filepos.Position) {
+	switch typedVal := val.(type) {
+	case *yamlmeta.Map:
+		typedVal.Position = pos
+		for _, item := range typedVal.Items {
+			item.Position = pos
+			s.setPositions(item.Value, pos)
+		}
+	case *yamlmeta.Array:
+		typedVal.Position = pos
+		for _, item := range typedVal.Items {
+			item.Position = pos
+			s.setPositions(item.Value, pos)
+		}
+	}
+}
+
 func (DataValuesFlags) libraryRefAndKey(key string) (string, string, error) {
 	const (
 		libraryKeySep = ":"
@@ -229,7 +549,7 @@ func (DataValuesFlags) libraryRefAndKey(key string) (string, string, error) {
 	}
 }
 
-func (s *DataValuesFlags) buildOverlay(keyPieces []string, value interface{}, desc string) *yamlmeta.Document {
+func (s *DataValuesFlags) buildOverlay(keyPieces []string, value interface{}, op structmeta.AnnotationName, desc string) *yamlmeta.Document {
 	const (
 		missingOkSuffix = "+"
 	)
@@ -266,11 +586,47 @@ func (s *DataValuesFlags) buildOverlay(keyPieces []string, value interface{}, de
 
 	lastMapItem.Value = yamlmeta.NewASTFromInterface(value)
 
-	// Explicitly replace entire value at given key
-	// (this allows to specify non-scalar data values)
+	// Explicitly replace (or remove) entire value at given key
+	// (replacing allows to specify non-scalar data values)
 	existingAnns := template.NewAnnotations(lastMapItem)
-	existingAnns[yttoverlay.AnnotationReplace] = template.NodeAnnotation{}
+	existingAnns[op] = template.NodeAnnotation{}
 	lastMapItem.SetAnnotations(existingAnns)
 
 	return &yamlmeta.Document{Value: resultMap, Position: pos}
 }
+
+// kvOpFlag collects keys of --data-value-unset or --data-value-default,
+// recording their position relative to other KV flags
+func (s *DataValuesFlags) kvOpFlag(keys *[]string, positions *[]kvOpPosition) pflag.Value {
+	return kvOpFlagValue{flags: s, keys: keys, positions: positions}
+}
+
+type kvOpFlagValue struct {
+	flags     *DataValuesFlags
+	keys      *[]string
+	positions *[]kvOpPosition
+}
+
+var _ pflag.Value = kvOpFlagValue{}
+
+func (v kvOpFlagValue) Type() string { return "stringArray" }
+
+// String is empty for no values so that help does not show a default
+func (v kvOpFlagValue) String() string {
+	if len(*v.keys) == 0 {
+		return ""
+	}
+	return "[" + strings.Join(*v.keys, ",") + "]"
+}
+
+func (v kvOpFlagValue) Set(key string) error {
+	s := v.flags
+	*v.keys = append(*v.keys, key)
+	*v.positions = append(*v.positions, kvOpPosition{
+		Strings: len(s.KVsFromStrings),
+		YAML:    len(s.KVsFromYAML),
+		Files:   len(s.KVsFromFiles),
+		Seq:     len(s.unsetPositions) + len(s.defaultPositions),
+	})
+	return nil
+}
@@ -17,7 +17,7 @@ type FileMarksOpts struct {
 }
 
 func (s *FileMarksOpts) Set(cmd *cobra.Command) {
-	cmd.Flags().StringArrayVar(&s.FileMarks, "file-mark", nil, "File mark (ie change file path, mark as non-template) (format: file:key=value) (can be specified multiple times)")
+	cmd.Flags().StringArrayVar(&s.FileMarks, "file-mark", nil, "File mark (ie change file path, mark as non-template, mark as JSON Schema) (format: file:key=value) (can be specified multiple times)")
 }
 
 func (s *FileMarksOpts) Apply(filesToProcess []*files.File) ([]*files.File, error) {
@@ -74,6 +74,9 @@ func (s *FileMarksOpts) Apply(filesToProcess []*files.File) ([]*files.File, erro
 					case "data":
 						file.MarkType(files.TypeUnknown)
 						file.MarkTemplate(false)
+					case "json-schema": // used as data values schema
+						file.MarkType(files.TypeJSONSchema)
+						file.MarkTemplate(false)
 					default:
 						return nil, fmt.Errorf("Unknown value in file mark '%s'", mark)
 					}
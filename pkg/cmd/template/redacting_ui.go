@@ -0,0 +1,67 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package template
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/k14s/ytt/pkg/cmd/ui"
+)
+
+// redactingUI masks sensitive data values in warnings and debug output.
+// Sensitive values are only known once data values are calculated,
+// hence redaction starts with the call to setRedactFunc.
+type redactingUI struct {
+	ui         ui.UI
+	redactFunc func(string) string
+}
+
+var _ ui.UI = &redactingUI{}
+
+func newRedactingUI(ui ui.UI) *redactingUI {
+	return &redactingUI{ui: ui}
+}
+
+func (u *redactingUI) setRedactFunc(redactFunc func(string) string) {
+	u.redactFunc = redactFunc
+}
+
+func (u *redactingUI) redact(str string) string {
+	if u.redactFunc == nil {
+		return str
+	}
+	return u.redactFunc(str)
+}
+
+// Printf is not redacted since it is used to print templates' output
+func (u *redactingUI) Printf(str string, args ...interface{}) {
+	u.ui.Printf(str, args...)
+}
+
+func (u *redactingUI) Warnf(str string, args ...interface{}) {
+	u.ui.Warnf("%s", u.redact(fmt.Sprintf(str, args...)))
+}
+
+func (u *redactingUI) Debugf(str string, args ...interface{}) {
+	u.ui.Debugf("%s", u.redact(fmt.Sprintf(str, args...)))
+}
+
+func (u *redactingUI) DebugWriter() io.Writer {
+	return redactingWriter{u}
+}
+
+type redactingWriter struct {
+	ui *redactingUI
+}
+
+var _ io.Writer = redactingWriter{}
+
+func (w redactingWriter) Write(data []byte) (int, error) {
+	_, err := w.ui.ui.DebugWriter().Write([]byte(w.ui.redact(string(data))))
+	if err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
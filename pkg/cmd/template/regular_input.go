@@ -17,6 +17,9 @@ const (
 	regularFilesOutputTypeYAML = "yaml"
 	regularFilesOutputTypeJSON = "json"
 	regularFilesOutputTypePos  = "pos"
+
+	regularFilesOutputTypeOpenAPI    = "openapi-v3"
+	regularFilesOutputTypeJSONSchema = "json-schema"
 )
 
 type RegularFilesSourceOpts struct {
@@ -36,7 +39,7 @@ func (s *RegularFilesSourceOpts) Set(cmd *cobra.Command) {
 		"Delete given directory, and then create it with output files")
 	cmd.Flags().StringVar(&s.OutputFiles, "output-files", "", "Add output files to given directory")
 
-	cmd.Flags().StringVarP(&s.OutputType, "output", "o", regularFilesOutputTypeYAML, "Output type (yaml, json, pos)")
+	cmd.Flags().StringVarP(&s.OutputType, "output", "o", regularFilesOutputTypeYAML, "Output type (yaml, json, pos; openapi-v3, json-schema with --data-values-schema-inspect)")
 
 	cmd.Flags().BoolVar(&s.SymlinkAllowOpts.AllowAll, "dangerous-allow-all-symlink-destinations", false,
 		"Symlinks to all destinations are allowed")
@@ -87,9 +90,11 @@ func (s *RegularFilesSource) Output(out Output) error {
 	var printerFunc func(io.Writer) yamlmeta.DocumentPrinter
 
 	switch s.opts.OutputType {
-	case regularFilesOutputTypeYAML:
-		printerFunc = nil
-	case regularFilesOutputTypeJSON:
+	case regularFilesOutputTypeYAML, regularFilesOutputTypeOpenAPI:
+		if out.DocSetWithComments {
+			printerFunc = func(w io.Writer) yamlmeta.DocumentPrinter { return yamlmeta.NewYAMLPrinterWithComments(w) }
+		}
+	case regularFilesOutputTypeJSON, regularFilesOutputTypeJSONSchema:
 		printerFunc = func(w io.Writer) yamlmeta.DocumentPrinter { return yamlmeta.NewJSONPrinter(w) }
 	case regularFilesOutputTypePos:
 		printerFunc = func(w io.Writer) yamlmeta.DocumentPrinter {
@@ -562,6 +562,1618 @@ vpc: #@ data.values.vpc
 
 		assertYTTWorkflowSucceedsWithOutput(t, filesToProcess, expected, opts)
 	})
+	t.Run("values set in earlier data values files are kept when later files omit them", func(t *testing.T) {
+		schemaYAML := `#@schema/match data_values=True
+---
+port: 0
+host: ""
+`
+		dataValuesYAML1 := `#@data/values
+---
+port: 8080
+`
+		dataValuesYAML2 := `#@data/values
+---
+host: example.com
+`
+		templateYAML := `#@ load("@ytt:data", "data")
+---
+values: #@ data.values
+`
+		expected := `values:
+  port: 8080
+  host: example.com
+`
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("dataValues1.yml", []byte(dataValuesYAML1))),
+			files.MustNewFileFromSource(files.NewBytesSource("dataValues2.yml", []byte(dataValuesYAML2))),
+			files.MustNewFileFromSource(files.NewBytesSource("template.yml", []byte(templateYAML))),
+		})
+
+		assertYTTWorkflowSucceedsWithOutput(t, filesToProcess, expected, opts)
+	})
+}
+
+func TestDefaultAnnotation(t *testing.T) {
+	opts := cmdtpl.NewOptions()
+	opts.SchemaEnabled = true
+
+	t.Run("sets the default value separately from the inferred type", func(t *testing.T) {
+		schemaYAML := `#@schema/match data_values=True
+---
+#@schema/default ["foo.example.com", "bar.example.com"]
+hosts:
+- ""
+#@schema/default [{"name": "web"}]
+ports:
+- name: ""
+  port: 80
+#@schema/default 8080
+port: 0
+#@schema/default {"enabled": True}
+tls:
+  enabled: false
+  cert: ""
+`
+		templateYAML := `#@ load("@ytt:data", "data")
+---
+values: #@ data.values
+`
+		expected := `values:
+  hosts:
+  - foo.example.com
+  - bar.example.com
+  ports:
+  - name: web
+    port: 80
+  port: 8080
+  tls:
+    enabled: true
+    cert: ""
+`
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("template.yml", []byte(templateYAML))),
+		})
+
+		assertYTTWorkflowSucceedsWithOutput(t, filesToProcess, expected, opts)
+	})
+	t.Run("data values override the default", func(t *testing.T) {
+		schemaYAML := `#@schema/match data_values=True
+---
+#@schema/default ["foo.example.com"]
+hosts:
+- ""
+`
+		dataValuesYAML := `#@ load("@ytt:overlay", "overlay")
+#@data/values
+---
+#@overlay/replace
+hosts:
+- baz.example.com
+`
+		templateYAML := `#@ load("@ytt:data", "data")
+---
+hosts: #@ data.values.hosts
+`
+		expected := `hosts:
+- baz.example.com
+`
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("dataValues.yml", []byte(dataValuesYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("template.yml", []byte(templateYAML))),
+		})
+
+		assertYTTWorkflowSucceedsWithOutput(t, filesToProcess, expected, opts)
+	})
+	t.Run("array data values replace the default", func(t *testing.T) {
+		schemaYAML := `#@schema/match data_values=True
+---
+#@schema/default ["foo.example.com"]
+hosts:
+- ""
+#@schema/default [{"name": "web"}]
+ports:
+- name: ""
+`
+		dataValuesYAML1 := `#@data/values
+---
+hosts:
+- baz.example.com
+`
+		dataValuesYAML2 := `#@data/values
+---
+hosts:
+- qux.example.com
+`
+		templateYAML := `#@ load("@ytt:data", "data")
+---
+hosts: #@ data.values.hosts
+ports: #@ data.values.ports
+`
+		expected := `hosts:
+- baz.example.com
+- qux.example.com
+ports:
+- name: api
+`
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("dataValues1.yml", []byte(dataValuesYAML1))),
+			files.MustNewFileFromSource(files.NewBytesSource("dataValues2.yml", []byte(dataValuesYAML2))),
+			files.MustNewFileFromSource(files.NewBytesSource("template.yml", []byte(templateYAML))),
+		})
+
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+		opts.DataValuesFlags.KVsFromYAML = []string{"ports=[{\"name\": \"api\"}]"}
+
+		assertYTTWorkflowSucceedsWithOutput(t, filesToProcess, expected, opts)
+	})
+	t.Run("defaults of nested items are used when a parent map is defaulted", func(t *testing.T) {
+		schemaYAML := `#@schema/match data_values=True
+---
+clients:
+- config:
+    #@schema/default ["read"]
+    flags:
+    - ""
+`
+		dataValuesYAML := `#@data/values
+---
+clients:
+- {}
+`
+		templateYAML := `#@ load("@ytt:data", "data")
+---
+clients: #@ data.values.clients
+`
+		expected := `clients:
+- config:
+    flags:
+    - read
+`
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("dataValues.yml", []byte(dataValuesYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("template.yml", []byte(templateYAML))),
+		})
+
+		assertYTTWorkflowSucceedsWithOutput(t, filesToProcess, expected, opts)
+	})
+	t.Run("fails when the default does not conform to the type", func(t *testing.T) {
+		schemaYAML := `#@schema/match data_values=True
+---
+#@schema/default [1, 2]
+hosts:
+- ""
+`
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+		})
+		expectedErr := `Invalid @schema/default value for 'hosts' (by schema.yml:4):
+schema.yml:4 | hosts:
+             |
+             | TYPE MISMATCH - the value of this item is not what schema expected:
+             |      found: integer
+             |   expected: string (by schema.yml:5)`
+
+		assertYTTWorkflowFailsWithErrorMessage(t, filesToProcess, expectedErr, opts)
+	})
+	t.Run("fails when not given exactly one argument", func(t *testing.T) {
+		schemaYAML := `#@schema/match data_values=True
+---
+#@schema/default
+port: 0
+`
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+		})
+		expectedErr := `
+schema.yml:4 | port: 0
+             |
+             | INVALID SCHEMA - expected @schema/default annotation to have exactly one argument, but found 0`
+
+		assertYTTWorkflowFailsWithErrorMessage(t, filesToProcess, expectedErr, opts)
+	})
+}
+
+func TestAnyTypeAnnotation(t *testing.T) {
+	opts := cmdtpl.NewOptions()
+	opts.SchemaEnabled = true
+
+	t.Run("allows any value under the annotated map item", func(t *testing.T) {
+		schemaYAML := `#@schema/match data_values=True
+---
+name: ""
+#@schema/type any=True
+extraLabels:
+  team: platform
+#@schema/type any=True
+podSpec: null
+`
+		dataValuesYAML := `#@data/values
+---
+extraLabels:
+  tier: 1
+  components:
+  - web
+  - worker
+podSpec:
+  containers:
+  - name: app
+    resources: {}
+`
+		templateYAML := `#@ load("@ytt:data", "data")
+---
+values: #@ data.values
+`
+		expected := `values:
+  name: ""
+  extraLabels:
+    tier: 1
+    components:
+    - web
+    - worker
+  podSpec:
+    containers:
+    - name: app
+      resources: {}
+`
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("dataValues.yml", []byte(dataValuesYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("template.yml", []byte(templateYAML))),
+		})
+
+		assertYTTWorkflowSucceedsWithOutput(t, filesToProcess, expected, opts)
+	})
+	t.Run("uses the value in schema, as-is, as the default", func(t *testing.T) {
+		schemaYAML := `#@schema/match data_values=True
+---
+#@schema/type any=True
+hosts:
+- foo.example.com
+- bar.example.com
+#@schema/type any=True
+podSpec: null
+`
+		templateYAML := `#@ load("@ytt:data", "data")
+---
+values: #@ data.values
+`
+		expected := `values:
+  hosts:
+  - foo.example.com
+  - bar.example.com
+  podSpec: null
+`
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("template.yml", []byte(templateYAML))),
+		})
+
+		assertYTTWorkflowSucceedsWithOutput(t, filesToProcess, expected, opts)
+	})
+	t.Run("allows any value in array items", func(t *testing.T) {
+		schemaYAML := `#@schema/match data_values=True
+---
+args:
+#@schema/type any=True
+- ""
+`
+		dataValuesYAML := `#@data/values
+---
+args:
+- --verbose
+- 3
+- {debug: true}
+`
+		templateYAML := `#@ load("@ytt:data", "data")
+---
+args: #@ data.values.args
+`
+		expected := `args:
+- --verbose
+- 3
+- debug: true
+`
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("dataValues.yml", []byte(dataValuesYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("template.yml", []byte(templateYAML))),
+		})
+
+		assertYTTWorkflowSucceedsWithOutput(t, filesToProcess, expected, opts)
+	})
+	t.Run("when any=False, the type is inferred", func(t *testing.T) {
+		schemaYAML := `#@schema/match data_values=True
+---
+#@schema/type any=False
+port: 0
+`
+		dataValuesYAML := `#@data/values
+---
+port: localhost
+`
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("dataValues.yml", []byte(dataValuesYAML))),
+		})
+		expectedErr := `
+dataValues.yml:3 | port: localhost
+                 |
+                 | TYPE MISMATCH - the value of this item is not what schema expected:
+                 |      found: string
+                 |   expected: integer (by schema.yml:4)`
+
+		assertYTTWorkflowFailsWithErrorMessage(t, filesToProcess, expectedErr, opts)
+	})
+	t.Run("siblings of an any typed item are still type checked", func(t *testing.T) {
+		schemaYAML := `#@schema/match data_values=True
+---
+#@schema/type any=True
+extraLabels: {}
+replicas: 1
+`
+		dataValuesYAML := `#@data/values
+---
+extraLabels:
+  foo: bar
+replicas: "3"
+`
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("dataValues.yml", []byte(dataValuesYAML))),
+		})
+		expectedErr := `
+dataValues.yml:5 | replicas: "3"
+                 |
+                 | TYPE MISMATCH - the value of this item is not what schema expected:
+                 |      found: string
+                 |   expected: integer (by schema.yml:5)`
+
+		assertYTTWorkflowFailsWithErrorMessage(t, filesToProcess, expectedErr, opts)
+	})
+}
+
+func TestDocumentationAnnotations(t *testing.T) {
+	opts := cmdtpl.NewOptions()
+	opts.SchemaEnabled = true
+
+	t.Run("descriptions and examples do not affect data values", func(t *testing.T) {
+		schemaYAML := `#@schema/match data_values=True
+---
+#@schema/desc "Number of pods to run"
+#@schema/examples ("High availability", 3), ("Scaled down", 0)
+replicas: 1
+`
+		templateYAML := `#@ load("@ytt:data", "data")
+---
+replicas: #@ data.values.replicas
+`
+		expected := `replicas: 1
+`
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("template.yml", []byte(templateYAML))),
+		})
+
+		assertYTTWorkflowSucceedsWithOutput(t, filesToProcess, expected, opts)
+	})
+	t.Run("setting a deprecated data value reports a warning", func(t *testing.T) {
+		schemaYAML := `#@schema/match data_values=True
+---
+app:
+  #@schema/deprecated "use 'replicas' instead"
+  instances: 0
+  replicas: 1
+  #@schema/deprecated
+  legacy: false
+`
+		dataValuesYAML := `#@data/values
+---
+app:
+  instances: 3
+`
+		templateYAML := `#@ load("@ytt:data", "data")
+---
+replicas: #@ data.values.app.replicas
+`
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("dataValues.yml", []byte(dataValuesYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("template.yml", []byte(templateYAML))),
+		})
+
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+		opts.DataValuesFlags = cmdtpl.DataValuesFlags{
+			KVsFromYAML: []string{"app.legacy=true"},
+		}
+
+		stdout := bytes.NewBufferString("")
+		stderr := bytes.NewBufferString("")
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewCustomWriterTTY(false, stdout, stderr))
+		if out.Err != nil {
+			t.Fatalf("Expected RunWithFiles to succeed, but was error: %s", out.Err)
+		}
+
+		expectedStdErr := "Warning: data value 'app.instances' (set by dataValues.yml:4) is deprecated (by schema.yml:5): use 'replicas' instead\n" +
+			"Warning: data value 'app.legacy' (set by key 'app.legacy' (kv arg):1) is deprecated (by schema.yml:8)\n"
+
+		err := assertStdoutAndStderr(bytes.NewBuffer(out.Files[0].Bytes()), stderr, "replicas: 1\n", expectedStdErr)
+		if err != nil {
+			t.Fatalf("Assertion failed:\n %s", err)
+		}
+	})
+	t.Run("fails when an example does not conform to the type", func(t *testing.T) {
+		schemaYAML := `#@schema/match data_values=True
+---
+#@schema/examples ("Three", "3")
+replicas: 1
+`
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+		})
+		expectedErr := `Invalid @schema/examples value for 'replicas' (by schema.yml:4):
+schema.yml:4 | replicas: 1
+             |
+             | TYPE MISMATCH - the value of this item is not what schema expected:
+             |      found: string
+             |   expected: integer (by schema.yml:4)`
+
+		assertYTTWorkflowFailsWithErrorMessage(t, filesToProcess, expectedErr, opts)
+	})
+	t.Run("fails when an example is not a tuple", func(t *testing.T) {
+		schemaYAML := `#@schema/match data_values=True
+---
+#@schema/examples 3
+replicas: 1
+`
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+		})
+		expectedErr := `
+schema.yml:4 | replicas: 1
+             |
+             | INVALID SCHEMA - expected @schema/examples annotation arguments to be tuples of two values, but found 3
+             |   (hint: specify each example as a tuple of description and value (e.g. @schema/examples ("Three replicas", 3)))`
+
+		assertYTTWorkflowFailsWithErrorMessage(t, filesToProcess, expectedErr, opts)
+	})
+}
+
+func TestValidationAnnotation(t *testing.T) {
+	opts := cmdtpl.NewOptions()
+	opts.SchemaEnabled = true
+
+	t.Run("succeeds when all values satisfy their validations", func(t *testing.T) {
+		schemaYAML := `#@ def is_even(val):
+#@   return val % 2 == 0
+#@ end
+
+#@schema/match data_values=True
+---
+#@schema/validation min=1, max=10
+replicas: 1
+#@schema/validation one_of=["debug", "info", "warn"]
+logLevel: info
+#@schema/validation min_len=1, max_len=3
+hosts:
+#@schema/validation format="hostname"
+- ""
+#@schema/validation is_even
+shards: 2
+#@schema/validation format="duration"
+timeout: 30s
+#@schema/nullable
+#@schema/validation format="ip"
+address: ""
+`
+		dataValuesYAML := `#@data/values
+---
+replicas: 10
+logLevel: debug
+hosts:
+- example.com
+- foo.example.com
+shards: 4
+timeout: 1m30s
+`
+		templateYAML := `#@ load("@ytt:data", "data")
+---
+values: #@ data.values
+`
+		expected := `values:
+  replicas: 10
+  logLevel: debug
+  hosts:
+  - example.com
+  - foo.example.com
+  shards: 4
+  timeout: 1m30s
+  address: null
+`
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("dataValues.yml", []byte(dataValuesYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("template.yml", []byte(templateYAML))),
+		})
+
+		assertYTTWorkflowSucceedsWithOutput(t, filesToProcess, expected, opts)
+	})
+	t.Run("reports every violation once all data values are applied", func(t *testing.T) {
+		schemaYAML := `#@schema/match data_values=True
+---
+#@schema/validation min=0
+replicas: 1
+#@schema/validation one_of=["debug", "info"]
+logLevel: info
+ports:
+#@schema/validation format="port"
+- 0
+#@schema/validation lambda v: v.startswith("app-")
+name: app-foo
+`
+		dataValuesYAML1 := `#@data/values
+---
+replicas: -3
+logLevel: warn
+`
+		dataValuesYAML2 := `#@data/values
+---
+ports:
+- 8080
+- 70000
+name: foo
+`
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("dataValues1.yml", []byte(dataValuesYAML1))),
+			files.MustNewFileFromSource(files.NewBytesSource("dataValues2.yml", []byte(dataValuesYAML2))),
+		})
+		expectedErr := `Validating data values: 
+schema.yml:4 | replicas: 1
+             |
+             | INVALID VALUE - the value of this item does not satisfy schema validation:
+             |      found: -3
+             |   expected: a number greater than or equal to 0 (by schema.yml:4)
+
+
+schema.yml:6 | logLevel: info
+             |
+             | INVALID VALUE - the value of this item does not satisfy schema validation:
+             |      found: warn
+             |   expected: one of: "debug", "info" (by schema.yml:6)
+
+
+dataValues2.yml:5 | - 70000
+                  |
+                  | INVALID VALUE - the value of this item does not satisfy schema validation:
+                  |      found: 70000
+                  |   expected: a value of format 'port' (by schema.yml:9)
+
+
+schema.yml:11 | name: app-foo
+              |
+              | INVALID VALUE - the value of this item does not satisfy schema validation:
+              |      found: foo
+              |   expected: a value for which lambda() returns True (by schema.yml:11)
+`
+
+		assertYTTWorkflowFailsWithErrorMessage(t, filesToProcess, expectedErr, opts)
+	})
+	t.Run("reports errors raised by predicates", func(t *testing.T) {
+		schemaYAML := `#@ load("@ytt:assert", "assert")
+#@ def valid_name(val):
+#@   if len(val) > 5:
+#@     assert.fail("name is too long")
+#@   end
+#@   return True
+#@ end
+
+#@schema/match data_values=True
+---
+#@schema/validation valid_name
+name: app
+`
+		dataValuesYAML := `#@data/values
+---
+name: application
+`
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("dataValues.yml", []byte(dataValuesYAML))),
+		})
+		expectedErr := `
+schema.yml:12 | name: app
+              |
+              | INVALID VALUE - the value of this item does not satisfy schema validation:
+              |      found: application
+              |   expected: a value for which valid_name() returns True (by schema.yml:12)
+              |   (error: assert.fail: fail: name is too long)`
+
+		assertYTTWorkflowFailsWithErrorMessage(t, filesToProcess, expectedErr, opts)
+	})
+	t.Run("fails when given an unknown format", func(t *testing.T) {
+		schemaYAML := `#@schema/match data_values=True
+---
+#@schema/validation format="email"
+contact: ""
+`
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+		})
+		expectedErr := `
+schema.yml:4 | contact: ""
+             |
+             | INVALID SCHEMA - invalid @schema/validation annotation keyword argument 'format': unknown format 'email' (known formats: duration, hostname, ip, port)`
+
+		assertYTTWorkflowFailsWithErrorMessage(t, filesToProcess, expectedErr, opts)
+	})
+	t.Run("checks strings against a pattern", func(t *testing.T) {
+		schemaYAML := `#@schema/match data_values=True
+---
+#@schema/validation pattern="^v[0-9]+$"
+version: v1
+#@schema/validation pattern="^[a-z]+$"
+name: app
+`
+		dataValuesYAML := `#@data/values
+---
+version: v2
+name: App
+`
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("dataValues.yml", []byte(dataValuesYAML))),
+		})
+		expectedErr := `
+schema.yml:6 | name: app
+             |
+             | INVALID VALUE - the value of this item does not satisfy schema validation:
+             |      found: App
+             |   expected: a string matching regular expression '^[a-z]+$' (by schema.yml:6)`
+
+		assertYTTWorkflowFailsWithErrorMessage(t, filesToProcess, expectedErr, opts)
+	})
+	t.Run("fails when given an invalid pattern", func(t *testing.T) {
+		schemaYAML := `#@schema/match data_values=True
+---
+#@schema/validation pattern="[a-z"
+name: app
+`
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+		})
+		expectedErr := `
+schema.yml:4 | name: app
+             |
+             | INVALID SCHEMA - invalid @schema/validation annotation keyword argument 'pattern': invalid regular expression: error parsing regexp: missing closing ]: ` + "`[a-z`"
+
+		assertYTTWorkflowFailsWithErrorMessage(t, filesToProcess, expectedErr, opts)
+	})
+}
+
+func TestSchemaInspect(t *testing.T) {
+	schemaYAML := `#@schema/match data_values=True
+---
+#@schema/desc "Number of replicas"
+#@schema/examples ("Three replicas", 3), ("One replica", 1)
+replicas: 1
+#@schema/nullable
+name: ""
+#@schema/deprecated
+legacy: true
+hosts:
+- host: ""
+#@schema/type any=True
+extra:
+  key: value
+`
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+	})
+
+	t.Run("as OpenAPI v3 document", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+		opts.InspectSchema = true
+
+		expected := `openapi: 3.0.0
+info:
+  version: 0.1.0
+  title: Schema for data values, generated by ytt
+paths: {}
+components:
+  schemas:
+    dataValues:
+      type: object
+      additionalProperties: false
+      properties:
+        replicas:
+          type: integer
+          description: Number of replicas
+          x-example-description: Three replicas
+          example: 3
+          default: 1
+        name:
+          type: string
+          nullable: true
+          default: null
+        legacy:
+          type: boolean
+          deprecated: true
+          default: true
+        hosts:
+          type: array
+          items:
+            type: object
+            additionalProperties: false
+            properties:
+              host:
+                type: string
+                default: ""
+          default: []
+        extra:
+          nullable: true
+          default:
+            key: value
+`
+		assertYTTSchemaInspectSucceedsWithOutput(t, filesToProcess, expected, opts)
+	})
+	t.Run("as JSON Schema document", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+		opts.InspectSchema = true
+		opts.RegularFilesSourceOpts.OutputType = "json-schema"
+
+		expected := `$schema: https://json-schema.org/draft/2019-09/schema
+title: Schema for data values, generated by ytt
+type: object
+additionalProperties: false
+properties:
+  replicas:
+    type: integer
+    description: Number of replicas
+    examples:
+    - 3
+    - 1
+    default: 1
+  name:
+    type:
+    - string
+    - "null"
+    default: null
+  legacy:
+    type: boolean
+    deprecated: true
+    default: true
+  hosts:
+    type: array
+    items:
+      type: object
+      additionalProperties: false
+      properties:
+        host:
+          type: string
+          default: ""
+    default: []
+  extra:
+    default:
+      key: value
+`
+		assertYTTSchemaInspectSucceedsWithOutput(t, filesToProcess, expected, opts)
+	})
+	t.Run("fails when schema is not enabled", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.InspectSchema = true
+
+		expectedErr := "Inspecting data values schema: no schema document was found"
+		assertYTTWorkflowFailsWithErrorMessage(t, filesToProcess, expectedErr, opts)
+	})
+	t.Run("fails when schema output type is requested without inspecting schema", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+		opts.RegularFilesSourceOpts.OutputType = "openapi-v3"
+
+		expectedErr := "Output type 'openapi-v3' requires --data-values-schema-inspect"
+		assertYTTWorkflowFailsWithErrorMessage(t, filesToProcess, expectedErr, opts)
+	})
+}
+
+func TestJSONSchemaFileMark(t *testing.T) {
+	templateYAML := `#@ load("@ytt:data", "data")
+---
+values: #@ data.values
+`
+
+	t.Run("converts JSON Schema into schema", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+		opts.FileMarksOpts.FileMarks = []string{"schema.json:type=json-schema"}
+
+		schemaJSON := `{
+  "type": "object",
+  "required": ["name", "hosts"],
+  "properties": {
+    "name": {"type": "string"},
+    "nickname": {"type": "string", "description": "Shown instead of name"},
+    "replicas": {"type": "integer", "default": 2, "minimum": 1},
+    "logLevel": {"enum": ["debug", "info"], "default": "info"},
+    "hosts": {"type": "array", "items": {"$ref": "#/definitions/host"}},
+    "tls": {"type": "object", "nullable": true, "properties": {"enabled": {"type": "boolean", "default": true}}},
+    "labels": {"default": {"app": "foo"}}
+  },
+  "definitions": {
+    "host": {
+      "type": "object",
+      "required": ["name", "port"],
+      "properties": {"name": {"type": "string"}, "port": {"type": ["integer", "null"], "default": 443}}
+    }
+  }
+}
+`
+		dataValuesYAML := `#@data/values
+---
+name: app
+hosts:
+- name: example.com
+logLevel: debug
+`
+		expected := `values:
+  name: app
+  nickname: null
+  replicas: 2
+  logLevel: debug
+  hosts:
+  - name: example.com
+    port: 443
+  tls: null
+  labels:
+    app: foo
+`
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.json", []byte(schemaJSON))),
+			files.MustNewFileFromSource(files.NewBytesSource("dataValues.yml", []byte(dataValuesYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("template.yml", []byte(templateYAML))),
+		})
+
+		assertYTTWorkflowSucceedsWithOutput(t, filesToProcess, expected, opts)
+	})
+	t.Run("reports required properties without a default that were not provided", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+		opts.FileMarksOpts.FileMarks = []string{"schema.json:type=json-schema"}
+
+		schemaJSON := `{
+  "type": "object",
+  "required": ["name", "port", "hosts"],
+  "properties": {
+    "name": {"type": "string"},
+    "port": {"type": "integer", "default": 80},
+    "hosts": {"type": "array", "items": {"type": "object", "required": ["name"], "properties": {"name": {"type": "string"}}}}
+  }
+}
+`
+		dataValuesYAML := `#@data/values
+---
+hosts:
+- {}
+`
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.json", []byte(schemaJSON))),
+			files.MustNewFileFromSource(files.NewBytesSource("dataValues.yml", []byte(dataValuesYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("template.yml", []byte(templateYAML))),
+		})
+		expectedErr := `
+MISSING REQUIRED VALUES - 2 data value(s) marked with @schema/required were not provided:
+  - name (required by schema.json:5)
+  - hosts.0.name (required by schema.json:7)
+  (hint: provide values via data values files or flags, e.g. --data-value-yaml key=value)
+`
+
+		assertYTTWorkflowFailsWithErrorMessage(t, filesToProcess, expectedErr, opts)
+	})
+	t.Run("uses 'dataValues' schema of an OpenAPI document", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+		opts.FileMarksOpts.FileMarks = []string{"openapi.yml:type=json-schema"}
+
+		openAPIYAML := `openapi: 3.0.0
+info:
+  version: 0.1.0
+  title: Example
+paths: {}
+components:
+  schemas:
+    port:
+      type: integer
+      default: 8080
+    dataValues:
+      type: object
+      properties:
+        port:
+          $ref: '#/components/schemas/port'
+`
+		dataValuesYAML := `#@data/values
+---
+port: not-a-port
+`
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("openapi.yml", []byte(openAPIYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("dataValues.yml", []byte(dataValuesYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("template.yml", []byte(templateYAML))),
+		})
+		expectedErr := `dataValues.yml:3 | port: not-a-port
+                 |
+                 | TYPE MISMATCH - the value of this item is not what schema expected:
+                 |      found: string
+                 |   expected: integer (by openapi.yml:14)
+`
+
+		assertYTTWorkflowFailsWithErrorMessage(t, filesToProcess, expectedErr, opts)
+	})
+	t.Run("fails on recursive references", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+		opts.FileMarksOpts.FileMarks = []string{"schema.json:type=json-schema"}
+
+		schemaJSON := `{
+  "type": "object",
+  "properties": {"tree": {"$ref": "#/definitions/node"}},
+  "definitions": {
+    "node": {"type": "object", "properties": {"children": {"type": "array", "items": {"$ref": "#/definitions/node"}}}}
+  }
+}
+`
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.json", []byte(schemaJSON))),
+			files.MustNewFileFromSource(files.NewBytesSource("template.yml", []byte(templateYAML))),
+		})
+		expectedErr := `Converting JSON Schema 'schema.json': 
+schema.json:5 |     "node": {"type": "object", "properties": {"children": {"type": "array", "items": {"$ref": "#/definitions/node"}}}}
+              |
+              | INVALID SCHEMA - recursive JSON Schema references are not supported
+              |   (hint: data values schema must have a finite depth)`
+
+		assertYTTWorkflowFailsWithErrorMessage(t, filesToProcess, expectedErr, opts)
+	})
+	t.Run("fails on circular references", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+		opts.FileMarksOpts.FileMarks = []string{"schema.json:type=json-schema"}
+
+		schemaJSON := `{
+  "type": "object",
+  "properties": {"x": {"$ref": "#/$defs/a"}},
+  "$defs": {"a": {"$ref": "#/$defs/a"}}
+}
+`
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.json", []byte(schemaJSON))),
+			files.MustNewFileFromSource(files.NewBytesSource("template.yml", []byte(templateYAML))),
+		})
+		expectedErr := `Converting JSON Schema 'schema.json': 
+schema.json:4 |   "$defs": {"a": {"$ref": "#/$defs/a"}}
+              |
+              | INVALID SCHEMA - circular $ref '#/$defs/a'
+              |   (hint: reference must eventually resolve to a schema without $ref)`
+
+		assertYTTWorkflowFailsWithErrorMessage(t, filesToProcess, expectedErr, opts)
+	})
+}
+
+func TestStringValuesAreCoerced(t *testing.T) {
+	schemaYAML := `#@schema/match data_values=True
+---
+replicas: 1
+enabled: false
+name: ""
+hosts:
+- ""
+ports:
+- 0
+#@schema/type any=True
+extra: ""
+`
+	templateYAML := `#@ load("@ytt:data", "data")
+---
+values: #@ data.values
+`
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+		files.MustNewFileFromSource(files.NewBytesSource("template.yml", []byte(templateYAML))),
+	})
+
+	t.Run("converts strings from command line and env into types expected by schema", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+		opts.DataValuesFlags.EnvFromStrings = []string{"DVS"}
+		opts.DataValuesFlags.EnvironFunc = func() []string { return []string{"DVS_enabled=yes"} }
+		opts.DataValuesFlags.KVsFromStrings = []string{"replicas=3", "name=123", "hosts=a.com,b.com", "ports=80, 443", "extra=1"}
+
+		expected := `values:
+  replicas: 3
+  enabled: true
+  name: "123"
+  hosts:
+  - a.com
+  - b.com
+  ports:
+  - 80
+  - 443
+  extra: "1"
+`
+
+		assertYTTWorkflowSucceedsWithOutput(t, filesToProcess, expected, opts)
+	})
+	t.Run("reports strings that cannot be converted", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+		opts.DataValuesFlags.KVsFromStrings = []string{"ports=80,http"}
+
+		expectedErr := `key 'ports' (kv arg):1 |
+                       |
+                       | INVALID VALUE - the string could not be converted to the type schema expected:
+                       |      found: "80,http"
+                       |   expected: comma separated list of integer (by schema.yml:8)
+`
+
+		assertYTTWorkflowFailsWithErrorMessage(t, filesToProcess, expectedErr, opts)
+	})
+	t.Run("converts only strings that are booleans in YAML into booleans", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+		opts.DataValuesFlags.KVsFromStrings = []string{"enabled=1"}
+
+		expectedErr := `key 'enabled' (kv arg):1 |
+                         |
+                         | INVALID VALUE - the string could not be converted to the type schema expected:
+                         |      found: "1"
+                         |   expected: boolean (by schema.yml:4)
+`
+
+		assertYTTWorkflowFailsWithErrorMessage(t, filesToProcess, expectedErr, opts)
+	})
+	t.Run("does not convert values given as YAML", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+		opts.DataValuesFlags.KVsFromYAML = []string{"replicas='3'"}
+
+		expectedErr := `key 'replicas' (kv arg):1 |
+                          |
+                          | TYPE MISMATCH - the value of this item is not what schema expected:
+                          |      found: string
+                          |   expected: integer (by schema.yml:3)
+`
+
+		assertYTTWorkflowFailsWithErrorMessage(t, filesToProcess, expectedErr, opts)
+	})
+}
+
+func TestUnionTypeAnnotation(t *testing.T) {
+	schemaYAML := `#@schema/match data_values=True
+---
+#@schema/type one_of=[int, str]
+maxSurge: 25%
+#@schema/type one_of=["debug", "info", "warn"]
+logLevel: info
+#@schema/type one_of=[str, dict]
+config: ""
+ports:
+#@schema/type one_of=[int, str]
+- 0
+`
+	templateYAML := `#@ load("@ytt:data", "data")
+---
+values: #@ data.values
+`
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+		files.MustNewFileFromSource(files.NewBytesSource("template.yml", []byte(templateYAML))),
+	})
+
+	opts := cmdtpl.NewOptions()
+	opts.SchemaEnabled = true
+
+	t.Run("accepts values of any of the alternatives", func(t *testing.T) {
+		dataValuesYAML := `#@data/values
+---
+maxSurge: 2
+logLevel: warn
+#@overlay/replace
+config:
+  nested: true
+ports:
+- 80
+- http
+`
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("dataValues.yml", []byte(dataValuesYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("template.yml", []byte(templateYAML))),
+		})
+
+		expected := `values:
+  maxSurge: 2
+  logLevel: warn
+  config:
+    nested: true
+  ports:
+  - 80
+  - http
+`
+
+		assertYTTWorkflowSucceedsWithOutput(t, filesToProcess, expected, opts)
+	})
+	t.Run("converts strings from command line into the first matching alternative", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+		opts.DataValuesFlags.KVsFromStrings = []string{"maxSurge=2", "ports=80,http"}
+
+		expected := `values:
+  maxSurge: 2
+  logLevel: info
+  config: ""
+  ports:
+  - 80
+  - http
+`
+
+		assertYTTWorkflowSucceedsWithOutput(t, filesToProcess, expected, opts)
+	})
+	t.Run("reports values that are not one of the alternatives", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+		opts.DataValuesFlags.KVsFromStrings = []string{"logLevel=trace"}
+
+		expectedErr := `key 'logLevel' (kv arg):1 |
+                          |
+                          | TYPE MISMATCH - the value of this item is not one of the alternatives schema expected:
+                          |      found: "trace"
+                          |   expected: one of: "debug", "info", "warn" (by schema.yml:6)
+`
+
+		assertYTTWorkflowFailsWithErrorMessage(t, filesToProcess, expectedErr, opts)
+	})
+	t.Run("reports collections that are not one of the alternatives", func(t *testing.T) {
+		dataValuesYAML := `#@data/values
+---
+#@overlay/replace
+maxSurge:
+- 1
+`
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("dataValues.yml", []byte(dataValuesYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("template.yml", []byte(templateYAML))),
+		})
+
+		expectedErr := `dataValues.yml:4 | maxSurge:
+                 |
+                 | TYPE MISMATCH - the value of this item is not one of the alternatives schema expected:
+                 |      found: array
+                 |   expected: one of: integer, string (by schema.yml:4)
+`
+
+		assertYTTWorkflowFailsWithErrorMessage(t, filesToProcess, expectedErr, opts)
+	})
+	t.Run("fails when default is not one of the alternatives", func(t *testing.T) {
+		schemaYAML := `#@schema/match data_values=True
+---
+#@schema/type one_of=["debug", "info"]
+logLevel: trace
+`
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+		})
+
+		expectedErr := `
+schema.yml:4 | logLevel: trace
+             |
+             | INVALID SCHEMA - value of this item is not one of the alternatives given via @schema/type
+             |   (hint: set value to one of: "debug", "info")`
+
+		assertYTTWorkflowFailsWithErrorMessage(t, filesToProcess, expectedErr, opts)
+	})
+	t.Run("fails when alternatives are not types or values", func(t *testing.T) {
+		schemaYAML := `#@schema/match data_values=True
+---
+#@schema/type one_of=[int, None]
+maxSurge: 0
+`
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+		})
+
+		expectedErr := `
+schema.yml:4 | maxSurge: 0
+             |
+             | INVALID SCHEMA - `
+
+		assertYTTWorkflowFailsWithErrorMessage(t, filesToProcess, expectedErr, opts)
+	})
+}
+
+func TestRequiredAnnotation(t *testing.T) {
+	schemaYAML := `#@schema/match data_values=True
+---
+#@schema/required
+name: ""
+db:
+  #@schema/required
+  password: ""
+  port: 5432
+users:
+- role: viewer
+  #@schema/required
+  name: ""
+`
+	templateYAML := `#@ load("@ytt:data", "data")
+---
+values: #@ data.values
+`
+	dataValuesYAML := `#@data/values
+---
+users:
+- role: admin
+`
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+		files.MustNewFileFromSource(files.NewBytesSource("dataValues.yml", []byte(dataValuesYAML))),
+		files.MustNewFileFromSource(files.NewBytesSource("template.yml", []byte(templateYAML))),
+	})
+
+	t.Run("reports all missing values at once", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+
+		expectedErr := `
+MISSING REQUIRED VALUES - 3 data value(s) marked with @schema/required were not provided:
+  - name (required by schema.yml:4)
+  - db.password (required by schema.yml:7)
+  - users.0.name (required by schema.yml:12)
+  (hint: provide values via data values files or flags, e.g. --data-value-yaml key=value)
+`
+
+		assertYTTWorkflowFailsWithErrorMessage(t, filesToProcess, expectedErr, opts)
+	})
+	t.Run("does not list data values files when there are none", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("template.yml", []byte(templateYAML))),
+		})
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+		if out.Err == nil {
+			t.Fatalf("Expected RunWithFiles to fail")
+		}
+		if !strings.HasPrefix(out.Err.Error(), "Overlaying data values: \nMISSING REQUIRED VALUES") {
+			t.Fatalf("Expected error to not list data values files, but was: >>>%s<<<", out.Err)
+		}
+	})
+	t.Run("succeeds when all required values are provided", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+		opts.DataValuesFlags.KVsFromStrings = []string{"name=app", "db.password=secret"}
+		opts.DataValuesFlags.KVsFromYAML = []string{"users=[{name: alice}]"}
+
+		expected := `values:
+  name: app
+  db:
+    password: secret
+    port: 5432
+  users:
+  - name: alice
+    role: viewer
+`
+
+		assertYTTWorkflowSucceedsWithOutput(t, filesToProcess, expected, opts)
+	})
+	t.Run("fails when required value has a default", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+
+		schemaYAML := `#@schema/match data_values=True
+---
+#@schema/required
+#@schema/default "app"
+name: ""
+`
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+		})
+
+		expectedErr := `
+schema.yml:5 | name: ""
+             |
+             | INVALID SCHEMA - @schema/required and @schema/default annotations cannot be used together`
+
+		assertYTTWorkflowFailsWithErrorMessage(t, filesToProcess, expectedErr, opts)
+	})
+	t.Run("fails when required value is a collection", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+
+		schemaYAML := `#@schema/match data_values=True
+---
+#@schema/required
+db:
+  port: 5432
+`
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+		})
+
+		expectedErr := `
+schema.yml:4 | db:
+             |
+             | INVALID SCHEMA - expected @schema/required annotation to be on a scalar value, but found map`
+
+		assertYTTWorkflowFailsWithErrorMessage(t, filesToProcess, expectedErr, opts)
+	})
+}
+
+func TestSensitiveAnnotation(t *testing.T) {
+	schemaYAML := `#@schema/match data_values=True
+---
+db:
+  user: admin
+  #@schema/sensitive
+  #@schema/validation min_len=12
+  password: ""
+#@schema/sensitive
+tls:
+  key: ""
+  cert: ""
+#@schema/sensitive
+pin: 0
+`
+
+	t.Run("masks sensitive values (and nested values) when inspecting data values", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+		opts.DataValuesFlags.Inspect = true
+		opts.DataValuesFlags.KVsFromStrings = []string{"db.password=very-long-password", "tls.key=private-key"}
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+		})
+		expected := `db:
+  user: admin
+  password: (redacted)
+tls: (redacted)
+pin: (redacted)
+`
+
+		assertYTTSchemaInspectSucceedsWithOutput(t, filesToProcess, expected, opts)
+	})
+	t.Run("masks sensitive values in validation errors", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+		opts.DataValuesFlags.KVsFromStrings = []string{"db.password=hunter22"}
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+		})
+		expectedErr := `Validating data values: 
+key 'db.password' (kv arg):1 |
+                             |
+                             | INVALID VALUE - the value of this item does not satisfy schema validation:
+                             |      found: (redacted)
+                             |   expected: a length of at least 12 (by schema.yml:7)
+`
+
+		assertYTTWorkflowFailsWithErrorMessage(t, filesToProcess, expectedErr, opts)
+	})
+	t.Run("masks sensitive values in type errors", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+		opts.DataValuesFlags.KVsFromStrings = []string{"pin=12ab"}
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+		})
+		expectedErr := `key 'pin' (kv arg):1 |
+                     |
+                     | INVALID VALUE - the string could not be converted to the type schema expected:
+                     |      found: (redacted)
+                     |   expected: integer (by schema.yml:13)
+`
+
+		assertYTTWorkflowFailsWithErrorMessage(t, filesToProcess, expectedErr, opts)
+	})
+	t.Run("does not show lines of sensitive values in type errors", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+
+		dataValuesYAML := `#@data/values
+---
+tls:
+  key: 42
+`
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("dataValues.yml", []byte(dataValuesYAML))),
+		})
+		expectedErr := `dataValues.yml:4 |
+                 |
+                 | TYPE MISMATCH - the value of this item is not what schema expected:
+                 |      found: integer
+                 |   expected: string (by schema.yml:10)
+`
+
+		assertYTTWorkflowFailsWithErrorMessage(t, filesToProcess, expectedErr, opts)
+	})
+	t.Run("masks sensitive values of libraries in errors", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+
+		libConfigYAML := `#@ load("@ytt:data", "data")
+#@ load("@ytt:assert", "assert")
+---
+password: #@ assert.fail("password '{}' is not allowed".format(data.values.db.password))
+`
+		configYAML := `#@ load("@ytt:library", "library")
+#@ load("@ytt:template", "template")
+--- #@ template.replace(library.get("app").with_data_values({"db": {"password": "lib-s3cr3t-passw0rd"}}).eval())
+`
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte("#@schema/match data_values=True\n---\n{}\n"))),
+			files.MustNewFileFromSource(files.NewBytesSource("config.yml", []byte(configYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("_ytt_lib/app/schema.yml", []byte(schemaYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("_ytt_lib/app/config.yml", []byte(libConfigYAML))),
+		})
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+		if out.Err == nil {
+			t.Fatalf("Expected RunWithFiles to fail")
+		}
+		if strings.Contains(out.Err.Error(), "lib-s3cr3t-passw0rd") || !strings.Contains(out.Err.Error(), "password '(redacted)' is not allowed") {
+			t.Fatalf("Expected error to have sensitive value of library masked, but was: >>>%s<<<", out.Err)
+		}
+	})
+}
+
+func TestSchemaInLibrary(t *testing.T) {
+	libSchemaYAML := `#@schema/match data_values=True
+---
+replicas: 1
+name: ""
+`
+	libConfigYAML := `#@ load("@ytt:data", "data")
+---
+replicas: #@ data.values.replicas
+name: #@ data.values.name
+`
+
+	t.Run("fills in defaults and checks values given by the parent", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+		opts.DataValuesFlags.KVsFromYAML = []string{"@~backend:replicas=5"}
+
+		configYAML := `#@ load("@ytt:library", "library")
+#@ load("@ytt:template", "template")
+--- #@ template.replace(library.get("app", alias="frontend").with_data_values({"replicas": 3}).eval())
+--- #@ template.replace(library.get("app", alias="backend").eval())
+`
+		valuesYAML := `#@library/ref "@~frontend"
+#@data/values
+---
+name: web
+`
+		expected := `replicas: 3
+name: web
+---
+replicas: 5
+name: ""
+`
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("config.yml", []byte(configYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("values.yml", []byte(valuesYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("_ytt_lib/app/schema.yml", []byte(libSchemaYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("_ytt_lib/app/config.yml", []byte(libConfigYAML))),
+		})
+
+		assertYTTWorkflowSucceedsWithOutput(t, filesToProcess, expected, opts)
+	})
+	t.Run("reports values mistyped in with_data_values", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+
+		configYAML := `#@ load("@ytt:library", "library")
+#@ load("@ytt:template", "template")
+--- #@ template.replace(library.get("app", alias="frontend").with_data_values({"replicas": "3"}).eval())
+`
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("config.yml", []byte(configYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("_ytt_lib/app/schema.yml", []byte(libSchemaYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("_ytt_lib/app/config.yml", []byte(libConfigYAML))),
+		})
+		expectedErr := `- library.eval: Evaluating library 'app~frontend': Overlaying data values (in following order: additional data values): 
+    in <toplevel>
+      config.yml:3 | --- #@ template.replace(library.get("app", alias="frontend").with_data_values({"replicas": "3"}).eval())
+
+    reason:
+     key 'replicas' (library.with_data_values arg):1 |
+                                                     |
+                                                     | TYPE MISMATCH - the value of this item is not what schema expected:
+                                                     |      found: string
+                                                     |   expected: integer (by _ytt_lib/app/schema.yml:3)`
+
+		assertYTTWorkflowFailsWithErrorMessage(t, filesToProcess, expectedErr, opts)
+	})
+	t.Run("reports values mistyped in library data values documents", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.SchemaEnabled = true
+
+		configYAML := `#@ load("@ytt:library", "library")
+#@ load("@ytt:template", "template")
+--- #@ template.replace(library.get("app", alias="frontend").eval())
+`
+		valuesYAML := `#@library/ref "@~frontend"
+#@data/values
+---
+name: 12
+`
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("config.yml", []byte(configYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("values.yml", []byte(valuesYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("_ytt_lib/app/schema.yml", []byte(libSchemaYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("_ytt_lib/app/config.yml", []byte(libConfigYAML))),
+		})
+		expectedErr := `- library.eval: Evaluating library 'app~frontend': Overlaying data values (in following order: additional data values): 
+    in <toplevel>
+      config.yml:3 | --- #@ template.replace(library.get("app", alias="frontend").eval())
+
+    reason:
+     values.yml:4 | name: 12
+                  |
+                  | TYPE MISMATCH - the value of this item is not what schema expected:
+                  |      found: integer
+                  |   expected: string (by _ytt_lib/app/schema.yml:4)`
+
+		assertYTTWorkflowFailsWithErrorMessage(t, filesToProcess, expectedErr, opts)
+	})
 }
 
 func TestNoSchemaProvided(t *testing.T) {
@@ -646,7 +2258,7 @@ schema.yml:4 |   subnet_ids:
              | INVALID ARRAY DEFINITION IN SCHEMA - unable to determine the desired type
              |      found: 2 array items
              |   expected: exactly 1 array item, of the desired type
-             |   (hint: to add elements to the default value of an array (i.e. an empty list), declare them in a @data/values document)`
+             |   (hint: to add elements to the default value of an array (i.e. an empty list), declare them in a @data/values document or with @schema/default)`
 		assertYTTWorkflowFailsWithErrorMessage(t, filesToProcess, expectedErr, opts)
 	})
 	t.Run("array value with a nullable annotation", func(t *testing.T) {
@@ -668,6 +2280,40 @@ schema.yml:6 |   - 0
 
 		assertYTTWorkflowFailsWithErrorMessage(t, filesToProcess, expectedErr, opts)
 	})
+	t.Run("type annotation without keyword arguments", func(t *testing.T) {
+		schemaYAML := `#@schema/match data_values=True
+---
+#@schema/type
+foo: 0
+`
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+		})
+		expectedErr := `
+schema.yml:4 | foo: 0
+             |
+             | INVALID SCHEMA - expected @schema/type annotation to have a keyword argument
+             |   (hint: supported keyword arguments: 'any', 'one_of')`
+		assertYTTWorkflowFailsWithErrorMessage(t, filesToProcess, expectedErr, opts)
+	})
+	t.Run("type annotation with an unknown keyword argument", func(t *testing.T) {
+		schemaYAML := `#@schema/match data_values=True
+---
+#@schema/type unknown=True
+foo: 0
+`
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+		})
+		expectedErr := `
+schema.yml:4 | foo: 0
+             |
+             | INVALID SCHEMA - unknown @schema/type annotation keyword argument 'unknown'
+             |   (hint: supported keyword arguments: 'any', 'one_of')`
+		assertYTTWorkflowFailsWithErrorMessage(t, filesToProcess, expectedErr, opts)
+	})
 	t.Run("null value", func(t *testing.T) {
 		schemaYAML := `#@schema/match data_values=True
 ---
@@ -736,6 +2382,24 @@ func assertYTTWorkflowSucceedsWithOutput(t *testing.T, filesToProcess []*files.F
 	}
 }
 
+func assertYTTSchemaInspectSucceedsWithOutput(t *testing.T, filesToProcess []*files.File, expectedOut string, opts *cmdtpl.Options) {
+	t.Helper()
+	out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+	if out.Err != nil {
+		t.Fatalf("Expected RunWithFiles to succeed, but was error: %s", out.Err)
+	}
+
+	outBytes, err := out.DocSet.AsBytes()
+	if err != nil {
+		t.Fatalf("Expected marshaling inspected schema to succeed, but was error: %s", err)
+	}
+
+	if string(outBytes) != expectedOut {
+		diff := difflib.PPDiff(strings.Split(string(outBytes), "\n"), strings.Split(expectedOut, "\n"))
+		t.Errorf("Expected output to be inspected schema, differences:\n%s", diff)
+	}
+}
+
 func assertYTTWorkflowFailsWithErrorMessage(t *testing.T, filesToProcess []*files.File, expectedErr string, opts *cmdtpl.Options) {
 	t.Helper()
 	out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
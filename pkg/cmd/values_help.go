@@ -0,0 +1,179 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	cmdtpl "github.com/k14s/ytt/pkg/cmd/template"
+	"github.com/k14s/ytt/pkg/cmd/ui"
+	"github.com/k14s/ytt/pkg/files"
+	"github.com/k14s/ytt/pkg/schema"
+	"github.com/spf13/cobra"
+)
+
+const (
+	valuesHelpFormatTable    = "table"
+	valuesHelpFormatMarkdown = "markdown"
+)
+
+type ValuesHelpOptions struct {
+	Files         []string
+	FileMarksOpts cmdtpl.FileMarksOpts
+	Format        string
+	StrictYAML    bool
+	Debug         bool
+}
+
+func NewValuesHelpOptions() *ValuesHelpOptions {
+	return &ValuesHelpOptions{}
+}
+
+func NewValuesHelpCmd(o *ValuesHelpOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "values-help",
+		Short: "Describe data values of a library",
+		Long: `Describe data values of a library.
+
+Lists every data value defined by schema with its type, default, nullability and description
+(as given via @schema/desc). Markdown format is suitable for README files of published libraries.`,
+		RunE: func(_ *cobra.Command, _ []string) error { return o.Run() },
+	}
+	cmd.Flags().StringArrayVarP(&o.Files, "file", "f", nil, "File (ie local path, HTTP URL, -) (can be specified multiple times)")
+	o.FileMarksOpts.Set(cmd)
+	cmd.Flags().StringVar(&o.Format, "format", valuesHelpFormatTable, "Output format (table, markdown)")
+	cmd.Flags().BoolVarP(&o.StrictYAML, "strict", "s", false, "Configure to use _strict_ YAML subset")
+	cmd.Flags().BoolVar(&o.Debug, "debug", false, "Enable debug output")
+	return cmd
+}
+
+func (o *ValuesHelpOptions) Run() error {
+	ui := ui.NewTTY(o.Debug)
+	t1 := time.Now()
+
+	defer func() {
+		ui.Debugf("total: %s\n", time.Now().Sub(t1))
+	}()
+
+	filesToProcess, err := files.NewSortedFilesFromPaths(o.Files, files.SymlinkAllowOpts{})
+	if err != nil {
+		return err
+	}
+
+	help, err := o.RunWithFiles(filesToProcess, ui)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprint(os.Stdout, help)
+	return nil
+}
+
+// RunWithFiles returns description of data values defined by schema found in given files
+func (o *ValuesHelpOptions) RunWithFiles(filesToProcess []*files.File, ui ui.UI) (string, error) {
+	switch o.Format {
+	case "", valuesHelpFormatTable, valuesHelpFormatMarkdown:
+	default:
+		return "", fmt.Errorf("Expected format to be one of '%s' or '%s', but was '%s'",
+			valuesHelpFormatTable, valuesHelpFormatMarkdown, o.Format)
+	}
+
+	docSchema, err := loadDocumentSchema(filesToProcess, o.FileMarksOpts, o.StrictYAML, ui)
+	if err != nil {
+		return "", fmt.Errorf("Loading schema: %s", err)
+	}
+
+	values := schema.Help(docSchema.Allowed)
+	if len(values) == 0 {
+		return "No data values defined in schema\n", nil
+	}
+
+	if o.Format == valuesHelpFormatMarkdown {
+		return o.markdown(values), nil
+	}
+	return o.table(values), nil
+}
+
+func (o *ValuesHelpOptions) table(values []schema.ValueHelp) string {
+	var result strings.Builder
+
+	w := tabwriter.NewWriter(&result, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PATH\tTYPE\tDEFAULT\tNULLABLE\tDESCRIPTION")
+
+	for _, value := range values {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", value.Path, value.Type,
+			o.defaultDesc(value), o.yesNo(value.Nullable), strings.ReplaceAll(o.description(value), "\n", " "))
+	}
+	w.Flush()
+
+	// last column is padded even if empty
+	lines := strings.Split(result.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (o *ValuesHelpOptions) markdown(values []schema.ValueHelp) string {
+	var result strings.Builder
+
+	fmt.Fprintln(&result, "| Path | Type | Default | Nullable | Description |")
+	fmt.Fprintln(&result, "|------|------|---------|----------|-------------|")
+
+	for _, value := range values {
+		defaultVal := o.defaultDesc(value)
+		if !value.Required && !value.Sensitive {
+			defaultVal = "`" + defaultVal + "`"
+		}
+		fmt.Fprintf(&result, "| `%s` | %s | %s | %s | %s |\n", value.Path, o.markdownCell(value.Type),
+			o.markdownCell(defaultVal), o.yesNo(value.Nullable), o.markdownCell(o.description(value)))
+	}
+
+	return result.String()
+}
+
+func (o *ValuesHelpOptions) defaultDesc(value schema.ValueHelp) string {
+	switch {
+	case value.Required:
+		return "(required)"
+	case value.Sensitive:
+		// defaults of sensitive values are not shown (help may end up published)
+		return "(sensitive)"
+	case len(value.Default) == 0:
+		return "-"
+	default:
+		return value.Default
+	}
+}
+
+func (o *ValuesHelpOptions) description(value schema.ValueHelp) string {
+	desc := value.Description
+	if value.Deprecated != nil {
+		notice := "Deprecated"
+		if len(*value.Deprecated) > 0 {
+			notice += ": " + *value.Deprecated
+		}
+		if len(desc) > 0 {
+			desc += " "
+		}
+		desc += "(" + notice + ")"
+	}
+	return desc
+}
+
+func (o *ValuesHelpOptions) yesNo(val bool) string {
+	if val {
+		return "yes"
+	}
+	return "no"
+}
+
+func (o *ValuesHelpOptions) markdownCell(str string) string {
+	str = strings.ReplaceAll(str, "|", "\\|")
+	return strings.ReplaceAll(str, "\n", "<br>")
+}
@@ -0,0 +1,112 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/k14s/difflib"
+	"github.com/k14s/ytt/pkg/cmd"
+	"github.com/k14s/ytt/pkg/cmd/ui"
+	"github.com/k14s/ytt/pkg/files"
+)
+
+func TestValuesHelp(t *testing.T) {
+	schemaYAML := `#@schema/match data_values=True
+---
+#@schema/desc "Name of the app"
+name: app
+#@schema/required
+#@schema/desc "Password | secret"
+password: ""
+db:
+  port: 5432
+  #@schema/nullable
+  host: ""
+#@schema/desc "Hosts to route to"
+hosts:
+- name: ""
+  #@schema/deprecated "use tls"
+  insecure: false
+#@schema/type one_of=["debug", "info"]
+logLevel: info
+#@schema/sensitive
+token: hunter2-default
+#@schema/sensitive
+tls:
+  key: private-key
+`
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+	})
+
+	assertHelp := func(t *testing.T, opts *cmd.ValuesHelpOptions, expectedHelp string) {
+		help, err := opts.RunWithFiles(filesToProcess, ui.NewTTY(false))
+		if err != nil {
+			t.Fatalf("Expected values help to succeed, but was error: %s", err)
+		}
+		if help != expectedHelp {
+			diff := difflib.PPDiff(strings.Split(help, "\n"), strings.Split(expectedHelp, "\n"))
+			t.Fatalf("Expected help to match, differences:\n%s", diff)
+		}
+	}
+
+	t.Run("prints table", func(t *testing.T) {
+		expectedHelp := `PATH              TYPE                     DEFAULT      NULLABLE  DESCRIPTION
+name              string                   "app"        no        Name of the app
+password          string                   (required)   no        Password | secret
+db.port           integer                  5432         no
+db.host           string                   null         yes
+hosts             array                    []           no        Hosts to route to
+hosts[].name      string                   ""           no
+hosts[].insecure  boolean                  false        no        (Deprecated: use tls)
+logLevel          one of: "debug", "info"  "info"       no
+token             string                   (sensitive)  no
+tls.key           string                   (sensitive)  no
+`
+		assertHelp(t, cmd.NewValuesHelpOptions(), expectedHelp)
+	})
+
+	t.Run("prints markdown", func(t *testing.T) {
+		opts := cmd.NewValuesHelpOptions()
+		opts.Format = "markdown"
+
+		expectedHelp := "| Path | Type | Default | Nullable | Description |\n" +
+			"|------|------|---------|----------|-------------|\n" +
+			"| `name` | string | `\"app\"` | no | Name of the app |\n" +
+			"| `password` | string | (required) | no | Password \\| secret |\n" +
+			"| `db.port` | integer | `5432` | no |  |\n" +
+			"| `db.host` | string | `null` | yes |  |\n" +
+			"| `hosts` | array | `[]` | no | Hosts to route to |\n" +
+			"| `hosts[].name` | string | `\"\"` | no |  |\n" +
+			"| `hosts[].insecure` | boolean | `false` | no | (Deprecated: use tls) |\n" +
+			"| `logLevel` | one of: \"debug\", \"info\" | `\"info\"` | no |  |\n" +
+			"| `token` | string | (sensitive) | no |  |\n" +
+			"| `tls.key` | string | (sensitive) | no |  |\n"
+
+		assertHelp(t, opts, expectedHelp)
+	})
+
+	t.Run("fails on unknown format", func(t *testing.T) {
+		opts := cmd.NewValuesHelpOptions()
+		opts.Format = "html"
+
+		_, err := opts.RunWithFiles(filesToProcess, ui.NewTTY(false))
+		if err == nil || err.Error() != "Expected format to be one of 'table' or 'markdown', but was 'html'" {
+			t.Fatalf("Expected values help to fail, but was: %v", err)
+		}
+	})
+
+	t.Run("fails without schema", func(t *testing.T) {
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("values.yml", []byte("#@data/values\n---\nname: app\n"))),
+		})
+
+		_, err := cmd.NewValuesHelpOptions().RunWithFiles(filesToProcess, ui.NewTTY(false))
+		if err == nil || !strings.Contains(err.Error(), "Loading schema: no schema document was found") {
+			t.Fatalf("Expected values help to fail, but was: %v", err)
+		}
+	})
+}
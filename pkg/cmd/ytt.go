@@ -45,6 +45,8 @@ Docs for data values: https://carvel.dev/ytt/docs/latest/ytt-data-values/`
 	cmd.AddCommand(cmdtpl.NewCmd(cmdtpl.NewOptions())) // for backwards compat
 	cmd.AddCommand(NewFmtCmd(NewFmtOptions()))
 	cmd.AddCommand(NewWebsiteCmd(NewWebsiteOptions()))
+	cmd.AddCommand(NewSchemaCmd())
+	cmd.AddCommand(NewValuesHelpCmd(NewValuesHelpOptions()))
 
 	// Reconfigure Commands
 	cobrautil.VisitCommands(cmd, cobrautil.ReconfigureCmdWithSubcmd,
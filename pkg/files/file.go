@@ -25,6 +25,7 @@ const (
 	TypeYAML
 	TypeText
 	TypeStarlark
+	TypeJSONSchema
 )
 
 type File struct {
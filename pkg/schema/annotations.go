@@ -0,0 +1,294 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package schema
+
+import (
+	"fmt"
+
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/k14s/ytt/pkg/filepos"
+	"github.com/k14s/ytt/pkg/structmeta"
+	"github.com/k14s/ytt/pkg/template"
+	"github.com/k14s/ytt/pkg/template/core"
+	"github.com/k14s/ytt/pkg/yamlmeta"
+)
+
+const (
+	AnnotationSchemaType       structmeta.AnnotationName = "schema/type"
+	AnnotationSchemaDefault    structmeta.AnnotationName = "schema/default"
+	AnnotationSchemaDesc       structmeta.AnnotationName = "schema/desc"
+	AnnotationSchemaExamples   structmeta.AnnotationName = "schema/examples"
+	AnnotationSchemaDeprecated structmeta.AnnotationName = "schema/deprecated"
+	AnnotationSchemaSensitive  structmeta.AnnotationName = "schema/sensitive"
+
+	TypeAnnotationKwargAny   string = "any"
+	TypeAnnotationKwargOneOf string = "one_of"
+
+	// RedactedValue is shown in place of values marked as sensitive
+	RedactedValue = "(redacted)"
+)
+
+type TypeAnnotation struct {
+	Any   bool
+	OneOf *UnionType
+}
+
+// unionScalarTypes are builtins that may be given to @schema/type one_of=[...] to allow values of a type
+var unionScalarTypes = map[string]interface{}{
+	"str":  "",
+	"int":  0,
+	"bool": false,
+}
+
+func NewTypeAnnotation(node yamlmeta.Node) (TypeAnnotation, error) {
+	var annotation TypeAnnotation
+
+	anns := template.NewAnnotations(node)
+	if !anns.Has(AnnotationSchemaType) {
+		return annotation, nil
+	}
+
+	supportedKwargs := fmt.Sprintf("'%s', '%s'", TypeAnnotationKwargAny, TypeAnnotationKwargOneOf)
+
+	kwargs := anns.Kwargs(AnnotationSchemaType)
+	if len(kwargs) == 0 {
+		return annotation, NewInvalidSchemaError(node,
+			fmt.Sprintf("expected @%s annotation to have a keyword argument", AnnotationSchemaType),
+			fmt.Sprintf("supported keyword arguments: %s", supportedKwargs))
+	}
+
+	for _, kwarg := range kwargs {
+		kwargName := string(kwarg[0].(starlark.String))
+		switch kwargName {
+		case TypeAnnotationKwargAny:
+			isAny, err := core.NewStarlarkValue(kwarg[1]).AsBool()
+			if err != nil {
+				return annotation, NewInvalidSchemaError(node,
+					fmt.Sprintf("expected @%s annotation keyword argument '%s' to be a boolean", AnnotationSchemaType, kwargName),
+					fmt.Sprintf("use '%s=True' or '%s=False'", TypeAnnotationKwargAny, TypeAnnotationKwargAny))
+			}
+			annotation.Any = isAny
+		case TypeAnnotationKwargOneOf:
+			unionType, err := newUnionType(node, kwarg[1])
+			if err != nil {
+				return annotation, err
+			}
+			annotation.OneOf = unionType
+		default:
+			return annotation, NewInvalidSchemaError(node,
+				fmt.Sprintf("unknown @%s annotation keyword argument '%s'", AnnotationSchemaType, kwargName),
+				fmt.Sprintf("supported keyword arguments: %s", supportedKwargs))
+		}
+	}
+
+	if annotation.Any && annotation.OneOf != nil {
+		return annotation, NewInvalidSchemaError(node,
+			fmt.Sprintf("expected @%s annotation to have either '%s' or '%s' keyword argument, but found both",
+				AnnotationSchemaType, TypeAnnotationKwargAny, TypeAnnotationKwargOneOf), "")
+	}
+
+	return annotation, nil
+}
+
+// newUnionType builds union from types (e.g. int, str) and values (e.g. "auto") given to one_of
+func newUnionType(node yamlmeta.Node, alternatives starlark.Value) (*UnionType, error) {
+	hint := "specify types (str, int, bool, dict, list) and/or values (e.g. one_of=[int, \"auto\"])"
+
+	_, isString := alternatives.(starlark.String)
+	iterable, isIterable := alternatives.(starlark.Iterable)
+	if isString || !isIterable {
+		return nil, NewInvalidSchemaError(node,
+			fmt.Sprintf("expected @%s annotation keyword argument '%s' to be a list, but was %s",
+				AnnotationSchemaType, TypeAnnotationKwargOneOf, alternatives.Type()), hint)
+	}
+
+	unionType := &UnionType{Position: node.GetPosition()}
+
+	iter := iterable.Iterate()
+	defer iter.Done()
+	var alternative starlark.Value
+	for iter.Next(&alternative) {
+		switch typedAlternative := alternative.(type) {
+		case *starlark.Builtin:
+			name := typedAlternative.Name()
+			zeroValue, isScalar := unionScalarTypes[name]
+			switch {
+			case typedAlternative != starlark.Universe[name]:
+				// only builtins from starlark's universe name types
+			case isScalar:
+				unionType.ScalarTypes = append(unionType.ScalarTypes, &ScalarType{Value: zeroValue, Position: node.GetPosition()})
+				continue
+			case name == "dict":
+				unionType.AllowsMap = true
+				continue
+			case name == "list":
+				unionType.AllowsArray = true
+				continue
+			}
+		case starlark.String, starlark.Int, starlark.Float, starlark.Bool:
+			unionType.Values = append(unionType.Values, typedAlternative)
+			continue
+		}
+		return nil, NewInvalidSchemaError(node,
+			fmt.Sprintf("unsupported alternative '%s' in @%s annotation keyword argument '%s'",
+				alternative.String(), AnnotationSchemaType, TypeAnnotationKwargOneOf), hint)
+	}
+
+	if len(unionType.ScalarTypes) == 0 && len(unionType.Values) == 0 && !unionType.AllowsMap && !unionType.AllowsArray {
+		return nil, NewInvalidSchemaError(node,
+			fmt.Sprintf("expected @%s annotation keyword argument '%s' to have at least one alternative",
+				AnnotationSchemaType, TypeAnnotationKwargOneOf), hint)
+	}
+
+	return unionType, nil
+}
+
+type DefaultAnnotation struct {
+	Value interface{}
+}
+
+// NewDefaultAnnotation returns nil when node is not annotated with @schema/default
+func NewDefaultAnnotation(node yamlmeta.Node) (*DefaultAnnotation, error) {
+	anns := template.NewAnnotations(node)
+	if !anns.Has(AnnotationSchemaDefault) {
+		return nil, nil
+	}
+
+	if len(anns.Kwargs(AnnotationSchemaDefault)) > 0 {
+		return nil, NewInvalidSchemaError(node,
+			fmt.Sprintf("expected @%s annotation to not have keyword arguments", AnnotationSchemaDefault), "")
+	}
+
+	args := anns.Args(AnnotationSchemaDefault)
+	if args.Len() != 1 {
+		return nil, NewInvalidSchemaError(node,
+			fmt.Sprintf("expected @%s annotation to have exactly one argument, but found %d", AnnotationSchemaDefault, args.Len()),
+			"specify the default value (e.g. @schema/default [\"example.com\"])")
+	}
+
+	value := yamlmeta.NewASTFromInterface(core.NewStarlarkValue(args.Index(0)).AsGoValue())
+	setUnknownPositions(value, node.GetPosition())
+
+	return &DefaultAnnotation{Value: value}, nil
+}
+
+type Example struct {
+	Description string
+	Value       interface{}
+}
+
+// NewDescAnnotation returns an empty string when node is not annotated with @schema/desc
+func NewDescAnnotation(node yamlmeta.Node) (string, error) {
+	anns := template.NewAnnotations(node)
+	if !anns.Has(AnnotationSchemaDesc) {
+		return "", nil
+	}
+
+	args := anns.Args(AnnotationSchemaDesc)
+	if args.Len() != 1 {
+		return "", NewInvalidSchemaError(node,
+			fmt.Sprintf("expected @%s annotation to have exactly one argument, but found %d", AnnotationSchemaDesc, args.Len()),
+			"describe the data value (e.g. @schema/desc \"Number of replicas to run\")")
+	}
+
+	desc, err := core.NewStarlarkValue(args.Index(0)).AsString()
+	if err != nil {
+		return "", NewInvalidSchemaError(node,
+			fmt.Sprintf("expected @%s annotation argument to be a string", AnnotationSchemaDesc), "")
+	}
+	return desc, nil
+}
+
+// NewExamplesAnnotation returns nil when node is not annotated with @schema/examples
+func NewExamplesAnnotation(node yamlmeta.Node) ([]Example, error) {
+	anns := template.NewAnnotations(node)
+	if !anns.Has(AnnotationSchemaExamples) {
+		return nil, nil
+	}
+
+	hint := "specify each example as a tuple of description and value (e.g. @schema/examples (\"Three replicas\", 3))"
+
+	args := anns.Args(AnnotationSchemaExamples)
+	if args.Len() == 0 {
+		return nil, NewInvalidSchemaError(node,
+			fmt.Sprintf("expected @%s annotation to have at least one argument", AnnotationSchemaExamples), hint)
+	}
+
+	var examples []Example
+	for _, arg := range args {
+		exampleTuple, ok := arg.(starlark.Tuple)
+		if !ok || exampleTuple.Len() != 2 {
+			return nil, NewInvalidSchemaError(node,
+				fmt.Sprintf("expected @%s annotation arguments to be tuples of two values, but found %s", AnnotationSchemaExamples, arg.String()), hint)
+		}
+
+		desc, err := core.NewStarlarkValue(exampleTuple.Index(0)).AsString()
+		if err != nil {
+			return nil, NewInvalidSchemaError(node,
+				fmt.Sprintf("expected @%s annotation example description to be a string, but found %s", AnnotationSchemaExamples, exampleTuple.Index(0).String()), hint)
+		}
+
+		value := yamlmeta.NewASTFromInterface(core.NewStarlarkValue(exampleTuple.Index(1)).AsGoValue())
+		setUnknownPositions(value, node.GetPosition())
+
+		examples = append(examples, Example{Description: desc, Value: value})
+	}
+	return examples, nil
+}
+
+// NewDeprecatedAnnotation returns false when node is not annotated with @schema/deprecated
+func NewDeprecatedAnnotation(node yamlmeta.Node) (string, bool, error) {
+	anns := template.NewAnnotations(node)
+	if !anns.Has(AnnotationSchemaDeprecated) {
+		return "", false, nil
+	}
+
+	args := anns.Args(AnnotationSchemaDeprecated)
+	switch args.Len() {
+	case 0:
+		return "", true, nil
+	case 1:
+		notice, err := core.NewStarlarkValue(args.Index(0)).AsString()
+		if err != nil {
+			return "", false, NewInvalidSchemaError(node,
+				fmt.Sprintf("expected @%s annotation argument to be a string", AnnotationSchemaDeprecated), "")
+		}
+		return notice, true, nil
+	default:
+		return "", false, NewInvalidSchemaError(node,
+			fmt.Sprintf("expected @%s annotation to have at most one argument, but found %d", AnnotationSchemaDeprecated, args.Len()),
+			"explain what to use instead (e.g. @schema/deprecated \"use 'replicas' instead\")")
+	}
+}
+
+// setUnknownPositions attributes nodes built from a Starlark value
+// to the location of the annotation that produced them
+func setUnknownPositions(val interface{}, position *filepos.Position) {
+	switch typedVal := val.(type) {
+	case *yamlmeta.Map:
+		if !typedVal.Position.IsKnown() {
+			typedVal.Position = position
+		}
+		for _, item := range typedVal.Items {
+			setUnknownPositions(item, position)
+		}
+	case *yamlmeta.MapItem:
+		if !typedVal.Position.IsKnown() {
+			typedVal.Position = position
+		}
+		setUnknownPositions(typedVal.Value, position)
+	case *yamlmeta.Array:
+		if !typedVal.Position.IsKnown() {
+			typedVal.Position = position
+		}
+		for _, item := range typedVal.Items {
+			setUnknownPositions(item, position)
+		}
+	case *yamlmeta.ArrayItem:
+		if !typedVal.Position.IsKnown() {
+			typedVal.Position = position
+		}
+		setUnknownPositions(typedVal.Value, position)
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package schema
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/k14s/ytt/pkg/yamlmeta"
+)
+
+const coercedListSep = ","
+
+// CoerceStringValues converts string values within node (e.g. given via command line flags)
+// into values of types that schema expects (i.e. after type has been assigned).
+// Strings are converted into integers, booleans and (comma separated) lists of those.
+func CoerceStringValues(node yamlmeta.Node) (chk yamlmeta.TypeCheck) {
+	var itemType yamlmeta.Type
+	switch typedNode := node.(type) {
+	case *yamlmeta.MapItem:
+		itemType = typedNode.Type
+	case *yamlmeta.ArrayItem:
+		itemType = typedNode.Type
+	}
+
+	if itemType != nil {
+		if str, isStr := node.GetValues()[0].(string); isStr {
+			value, err := coerceString(node, str, itemType.GetValueType())
+			if err != nil {
+				chk.Violations = append(chk.Violations, err)
+				return
+			}
+			err = node.SetValue(value)
+			if err != nil {
+				panic(fmt.Sprintf("Internal inconsistency: setting coerced value: %s", err))
+			}
+			// coerced value may be a collection that needs to be typed
+			return itemType.AssignTypeTo(node.(yamlmeta.Typeable))
+		}
+	}
+
+	for _, val := range node.GetValues() {
+		if childNode, ok := val.(yamlmeta.Node); ok {
+			chk.Violations = append(chk.Violations, CoerceStringValues(childNode).Violations...)
+		}
+	}
+	return
+}
+
+// coerceString returns str unchanged when schema does not expect a scalar (or a list of scalars)
+// so that type check reports the mismatch
+func coerceString(node yamlmeta.Node, str string, valueType yamlmeta.Type) (interface{}, error) {
+	switch typedType := valueType.(type) {
+	case *ScalarType:
+		value, err := typedType.parse(str)
+		if err != nil {
+			return nil, NewInvalidStringValueError(node, str, typedType.String(), typedType.Position)
+		}
+		return value, nil
+
+	case *ArrayType:
+		itemValueType := typedType.ItemsType.GetValueType()
+		switch itemValueType.(type) {
+		case *ScalarType, *UnionType:
+		default:
+			return str, nil
+		}
+		array := &yamlmeta.Array{Position: node.GetPosition()}
+		if len(strings.TrimSpace(str)) == 0 {
+			return array, nil
+		}
+		for _, piece := range strings.Split(str, coercedListSep) {
+			value, err := coerceString(node, strings.TrimSpace(piece), itemValueType)
+			if err != nil {
+				return nil, NewInvalidStringValueError(node, str,
+					fmt.Sprintf("comma separated list of %s", itemValueType.String()), typedType.Position)
+			}
+			array.Items = append(array.Items, &yamlmeta.ArrayItem{Value: value, Position: node.GetPosition()})
+		}
+		return array, nil
+
+	case *UnionType:
+		return typedType.coerce(str), nil
+
+	default:
+		return str, nil
+	}
+}
+
+// coerce converts str into the first of union's types (in order given) that str could be
+// converted into, otherwise into one of union's values; str is returned unchanged when
+// nothing matches so that type check reports the mismatch
+func (u *UnionType) coerce(str string) interface{} {
+	for _, scalarType := range u.ScalarTypes {
+		if value, err := scalarType.parse(str); err == nil {
+			return value
+		}
+	}
+	if u.allows(str) {
+		return str
+	}
+	for _, scalarType := range []ScalarType{{Value: 0}, {Value: false}} {
+		if value, err := scalarType.parse(str); err == nil && u.allows(value) {
+			return value
+		}
+	}
+	return str
+}
+
+func (m ScalarType) parse(str string) (interface{}, error) {
+	switch m.Value.(type) {
+	case int:
+		return strconv.Atoi(strings.TrimSpace(str))
+	case bool:
+		return parseBool(strings.TrimSpace(str))
+	default:
+		return str, nil
+	}
+}
+
+// parseBool accepts the same booleans as YAML (e.g. 'true', 'yes', 'on'),
+// so that values are converted just like those given via --data-value-yaml
+func parseBool(str string) (bool, error) {
+	var value interface{}
+	err := yamlmeta.PlainUnmarshal([]byte(str), &value)
+	if err != nil {
+		return false, err
+	}
+	boolVal, isBool := value.(bool)
+	if !isBool {
+		return false, fmt.Errorf("Expected '%s' to be a boolean", str)
+	}
+	return boolVal, nil
+}
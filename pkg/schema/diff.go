@@ -0,0 +1,330 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package schema
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/k14s/ytt/pkg/filepos"
+	"github.com/k14s/ytt/pkg/yamlmeta"
+)
+
+// ChangeKind classifies a change by its effect on data values that were valid for the old schema
+type ChangeKind string
+
+const (
+	// ChangeBreaking may cause valid data values to be rejected by the new schema
+	ChangeBreaking ChangeKind = "breaking"
+	// ChangePotentiallyBreaking may reject valid data values depending on what they are (e.g. added validation rules)
+	ChangePotentiallyBreaking ChangeKind = "potentially breaking"
+	ChangeNonBreaking         ChangeKind = "non-breaking"
+)
+
+// Change describes a single difference between two versions of a schema
+type Change struct {
+	Path     string
+	Message  string
+	Kind     ChangeKind
+	Position *filepos.Position // of definition in new schema (or in old schema, if removed)
+}
+
+// Diff compares two schemas and classifies each difference as breaking or non-breaking
+func Diff(oldSchema, newSchema *DocumentType) []Change {
+	return diffTypes("", oldSchema.ValueType, newSchema.ValueType, newSchema.Position)
+}
+
+func diffTypes(path string, oldType, newType yamlmeta.Type, pos *filepos.Position) []Change {
+	oldMap, oldIsMap := oldType.(*MapType)
+	newMap, newIsMap := newType.(*MapType)
+	if oldIsMap && newIsMap {
+		return diffMaps(path, oldMap, newMap)
+	}
+
+	oldArray, oldIsArray := oldType.(*ArrayType)
+	newArray, newIsArray := newType.(*ArrayType)
+	if oldIsArray && newIsArray {
+		oldItemsType := oldArray.ItemsType.(*ArrayItemType)
+		newItemsType := newArray.ItemsType.(*ArrayItemType)
+		changes := diffTypes(path+"[]", oldItemsType.ValueType, newItemsType.ValueType, newItemsType.Position)
+		return append(changes, diffValidations(path+"[]", oldItemsType.Validation, newItemsType.Validation, newItemsType.Position)...)
+	}
+
+	widened := covers(newType, oldType)
+	narrowed := covers(oldType, newType)
+
+	switch {
+	case widened && narrowed:
+		return nil
+	case widened:
+		return []Change{{path, fmt.Sprintf("type widened from %s to %s", typeName(oldType), typeName(newType)), ChangeNonBreaking, pos}}
+	case narrowed:
+		return []Change{{path, fmt.Sprintf("type narrowed from %s to %s", typeName(oldType), typeName(newType)), ChangeBreaking, pos}}
+	default:
+		return []Change{{path, fmt.Sprintf("type changed from %s to %s", typeName(oldType), typeName(newType)), ChangeBreaking, pos}}
+	}
+}
+
+func diffMaps(path string, oldMap, newMap *MapType) []Change {
+	var changes []Change
+
+	for _, oldItem := range oldMap.Items {
+		itemPath := mapItemPath(path, oldItem.Key)
+		newItem, found := findMapItemType(newMap, oldItem.Key)
+		if !found {
+			changes = append(changes, Change{itemPath, "removed", ChangeBreaking, oldItem.Position})
+			continue
+		}
+		changes = append(changes, diffMapItems(itemPath, oldItem, newItem)...)
+	}
+
+	for _, newItem := range newMap.Items {
+		if _, found := findMapItemType(oldMap, newItem.Key); found {
+			continue
+		}
+		itemPath := mapItemPath(path, newItem.Key)
+		if isRequired(newItem) {
+			changes = append(changes, Change{itemPath, "added without a default (value is required)", ChangeBreaking, newItem.Position})
+		} else {
+			changes = append(changes, Change{itemPath, fmt.Sprintf("added with default %s", defaultDesc(newItem)), ChangeNonBreaking, newItem.Position})
+		}
+	}
+
+	return changes
+}
+
+func diffMapItems(path string, oldItem, newItem *MapItemType) []Change {
+	var changes []Change
+
+	switch {
+	case oldItem.IsNullable() && !newItem.IsNullable():
+		changes = append(changes, Change{path, "no longer nullable", ChangeBreaking, newItem.Position})
+	case !oldItem.IsNullable() && newItem.IsNullable():
+		changes = append(changes, Change{path, "became nullable", ChangeNonBreaking, newItem.Position})
+	}
+
+	switch {
+	case !oldItem.IsRequired() && newItem.IsRequired():
+		changes = append(changes, Change{path, "became required", ChangeBreaking, newItem.Position})
+	case oldItem.IsRequired() && !newItem.IsRequired():
+		changes = append(changes, Change{path, fmt.Sprintf("no longer required (defaults to %s)", defaultDesc(newItem)), ChangeNonBreaking, newItem.Position})
+	}
+
+	changes = append(changes, diffTypes(path, oldItem.ValueType, newItem.ValueType, newItem.Position)...)
+	changes = append(changes, diffValidations(path, oldItem.Validation, newItem.Validation, newItem.Position)...)
+
+	// defaults of maps are described by defaults of their items (and required values have none)
+	_, isMap := newItem.ValueType.(*MapType)
+	hasDefaults := !oldItem.IsRequired() && !newItem.IsRequired()
+	if !isMap && hasDefaults {
+		defaultChanged := !equalDefaults(oldItem.DefaultValue, newItem.DefaultValue)
+		switch {
+		case isRequired(newItem) && (defaultChanged || !isRequired(oldItem)):
+			changes = append(changes, Change{path, fmt.Sprintf("default %s no longer satisfies validation (value is required)", defaultDesc(newItem)), ChangeBreaking, newItem.Position})
+		case defaultChanged:
+			changes = append(changes, Change{path, fmt.Sprintf("default changed from %s to %s", defaultDesc(oldItem), defaultDesc(newItem)), ChangeNonBreaking, newItem.Position})
+		}
+	}
+
+	return changes
+}
+
+// diffValidations compares validation rules by their descriptions: added rules may reject values
+// that were valid, depending on what they are; rules given as functions are compared only by name
+func diffValidations(path string, oldValidation, newValidation *Validation, pos *filepos.Position) []Change {
+	oldRules, newRules := oldValidation.ruleDescs(), newValidation.ruleDescs()
+
+	var changes []Change
+	for _, rule := range newRules {
+		if !containsString(oldRules, rule) {
+			changes = append(changes, Change{path, fmt.Sprintf("validation added: %s", rule), ChangePotentiallyBreaking, pos})
+		}
+	}
+	for _, rule := range oldRules {
+		if !containsString(newRules, rule) {
+			changes = append(changes, Change{path, fmt.Sprintf("validation removed: %s", rule), ChangeNonBreaking, pos})
+		}
+	}
+	return changes
+}
+
+func containsString(strs []string, str string) bool {
+	for _, s := range strs {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
+
+// covers indicates whether wider allows all values that narrower allows
+// (contents of maps and arrays are compared separately)
+func covers(wider, narrower yamlmeta.Type) bool {
+	if _, isAny := wider.(*AnyType); isAny || wider == nil {
+		return true
+	}
+
+	switch typedNarrower := narrower.(type) {
+	case *MapType:
+		return allowsMaps(wider)
+	case *ArrayType:
+		return allowsArrays(wider)
+	case *ScalarType:
+		switch typedWider := wider.(type) {
+		case *ScalarType:
+			return coversScalar(typedWider, typedNarrower)
+		case *UnionType:
+			for _, scalarType := range typedWider.ScalarTypes {
+				if coversScalar(scalarType, typedNarrower) {
+					return true
+				}
+			}
+		}
+		return false
+	case *UnionType:
+		for _, scalarType := range typedNarrower.ScalarTypes {
+			if !covers(wider, scalarType) {
+				return false
+			}
+		}
+		if typedNarrower.AllowsMap && !allowsMaps(wider) {
+			return false
+		}
+		if typedNarrower.AllowsArray && !allowsArrays(wider) {
+			return false
+		}
+		for _, value := range typedNarrower.Values {
+			if !allowsValue(wider, literalValue(value)) {
+				return false
+			}
+		}
+		return true
+	default:
+		// includes AnyType (wider is not any)
+		return false
+	}
+}
+
+func coversScalar(wider, narrower *ScalarType) bool {
+	chk := wider.CheckType(&yamlmeta.Scalar{Value: narrower.Value})
+	return !chk.HasViolations()
+}
+
+func allowsMaps(t yamlmeta.Type) bool {
+	switch typedType := t.(type) {
+	case *MapType:
+		return true
+	case *UnionType:
+		return typedType.AllowsMap
+	}
+	return false
+}
+
+func allowsArrays(t yamlmeta.Type) bool {
+	switch typedType := t.(type) {
+	case *ArrayType:
+		return true
+	case *UnionType:
+		return typedType.AllowsArray
+	}
+	return false
+}
+
+func allowsValue(t yamlmeta.Type, value interface{}) bool {
+	switch typedType := t.(type) {
+	case *ScalarType:
+		chk := typedType.CheckType(&yamlmeta.Scalar{Value: value})
+		return !chk.HasViolations()
+	case *UnionType:
+		return typedType.allows(value)
+	}
+	return false
+}
+
+func literalValue(value starlark.Value) interface{} {
+	switch typedValue := value.(type) {
+	case starlark.String:
+		return string(typedValue)
+	case starlark.Int:
+		i, _ := typedValue.Int64()
+		return i
+	case starlark.Float:
+		return float64(typedValue)
+	case starlark.Bool:
+		return bool(typedValue)
+	}
+	return nil
+}
+
+// isRequired indicates whether a value must be provided since default does not satisfy item's validation
+func isRequired(item *MapItemType) bool {
+	if item.IsRequired() {
+		return true
+	}
+	if item.Validation == nil || item.DefaultValue == nil {
+		return false
+	}
+	node := &yamlmeta.MapItem{Key: item.Key, Value: item.DefaultValue, Position: item.Position}
+	return len(item.Validation.Validate(node, item.DefaultValue, &starlark.Thread{Name: "schema-diff"})) > 0
+}
+
+// equalDefaults compares default values; numbers are equal regardless of whether they are integers or floats
+func equalDefaults(oldValue, newValue interface{}) bool {
+	oldValue, newValue = yamlmeta.NewGoFromAST(oldValue), yamlmeta.NewGoFromAST(newValue)
+	if oldNum, isNum := asFloat(oldValue); isNum {
+		if newNum, isNum := asFloat(newValue); isNum {
+			return oldNum == newNum
+		}
+	}
+	return reflect.DeepEqual(oldValue, newValue)
+}
+
+func asFloat(value interface{}) (float64, bool) {
+	switch typedValue := value.(type) {
+	case int:
+		return float64(typedValue), true
+	case int64:
+		return float64(typedValue), true
+	case uint64:
+		return float64(typedValue), true
+	case float64:
+		return typedValue, true
+	}
+	return 0, false
+}
+
+func findMapItemType(m *MapType, key interface{}) (*MapItemType, bool) {
+	for _, item := range m.Items {
+		if item.Key == key {
+			return item, true
+		}
+	}
+	return nil, false
+}
+
+func mapItemPath(path string, key interface{}) string {
+	if path == "" {
+		return fmt.Sprintf("%v", key)
+	}
+	return fmt.Sprintf("%s.%v", path, key)
+}
+
+func typeName(t yamlmeta.Type) string {
+	if t == nil {
+		return "null"
+	}
+	return t.String()
+}
+
+func defaultDesc(item *MapItemType) string {
+	value := yamlmeta.NewGoFromAST(item.DefaultValue)
+	if str, isStr := value.(string); isStr {
+		return fmt.Sprintf("%q", str)
+	}
+	if value == nil {
+		return "null"
+	}
+	return fmt.Sprintf("%v", value)
+}
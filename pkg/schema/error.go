@@ -33,6 +33,14 @@ func NewMismatchedTypeError(foundType yamlmeta.TypeWithValues, expectedType yaml
 	}
 }
 
+func NewUnionMismatchError(found yamlmeta.TypeWithValues, value interface{}, expected *UnionType) error {
+	return &unionMismatchError{
+		Found:    found,
+		Value:    value,
+		Expected: expected,
+	}
+}
+
 func NewUnexpectedKeyError(found *yamlmeta.MapItem, definition *filepos.Position) error {
 	return &unexpectedKeyError{
 		Found:                 found,
@@ -40,6 +48,29 @@ func NewUnexpectedKeyError(found *yamlmeta.MapItem, definition *filepos.Position
 	}
 }
 
+func NewInvalidValueError(found yamlmeta.Node, value interface{}, expected string, definition *filepos.Position, cause error) error {
+	return &invalidValueError{
+		Found:              found,
+		Value:              value,
+		Expected:           expected,
+		DefinitionPosition: definition,
+		Cause:              cause,
+	}
+}
+
+func NewInvalidStringValueError(found yamlmeta.Node, value string, expected string, definition *filepos.Position) error {
+	return &invalidStringValueError{
+		Found:              found,
+		Value:              value,
+		Expected:           expected,
+		DefinitionPosition: definition,
+	}
+}
+
+func NewInvalidValuesError(violations []error) error {
+	return &invalidValuesError{Violations: violations}
+}
+
 type invalidSchemaError struct {
 	Message string
 	Found   yamlmeta.Node
@@ -84,13 +115,14 @@ func (i invalidArrayDefinitionError) Error() string {
 }
 
 type mismatchedTypeError struct {
-	Found    yamlmeta.TypeWithValues
-	Expected yamlmeta.Type
+	Found     yamlmeta.TypeWithValues
+	Expected  yamlmeta.Type
+	Sensitive bool
 }
 
 func (t mismatchedTypeError) Error() string {
 	position := t.Found.GetPosition().AsCompactString()
-	lineContent := t.Found.GetPosition().GetLine()
+	lineContent := lineContentOf(t.Found.GetPosition(), t.Sensitive)
 
 	leftPadLength := len(position) + 1
 	msg := "\n"
@@ -116,6 +148,36 @@ func (t mismatchedTypeError) Error() string {
 	return msg
 }
 
+type unionMismatchError struct {
+	Found     yamlmeta.TypeWithValues
+	Value     interface{}
+	Expected  *UnionType
+	Sensitive bool
+}
+
+func (t unionMismatchError) Error() string {
+	position := t.Found.GetPosition().AsCompactString()
+	lineContent := lineContentOf(t.Found.GetPosition(), t.Sensitive)
+
+	found := t.Found.ValueTypeAsString()
+	if _, isNode := t.Value.(yamlmeta.Node); !isNode && t.Value != nil {
+		// alternatives may be values, hence show value itself
+		found = asStarlarkValue(t.Value).String()
+		if t.Sensitive {
+			found = RedactedValue
+		}
+	}
+
+	leftPadLength := len(position) + 1
+	msg := "\n"
+	msg += formatLine(leftPadLength, position, lineContent)
+	msg += formatLine(leftPadLength, "", "")
+	msg += formatLine(leftPadLength, "", "TYPE MISMATCH - the value of this item is not one of the alternatives schema expected:")
+	msg += formatLine(leftPadLength, "", fmt.Sprintf("     found: %s", found))
+	msg += formatLine(leftPadLength, "", fmt.Sprintf("  expected: %s (by %s)", t.Expected.String(), t.Expected.Position.AsCompactString()))
+	return msg
+}
+
 type unexpectedKeyError struct {
 	Found                 *yamlmeta.MapItem
 	MapDefinitionPosition *filepos.Position
@@ -137,6 +199,160 @@ func (t unexpectedKeyError) Error() string {
 	return msg
 }
 
+type invalidValueError struct {
+	Found              yamlmeta.Node
+	Value              interface{}
+	Expected           string
+	DefinitionPosition *filepos.Position
+	Cause              error
+	Sensitive          bool
+}
+
+func (e invalidValueError) Error() string {
+	position := e.Found.GetPosition().AsCompactString()
+	leftColumnSize := len(position) + 1
+	lineContent := lineContentOf(e.Found.GetPosition(), e.Sensitive)
+
+	msg := "\n"
+	msg += formatLine(leftColumnSize, position, lineContent)
+	msg += formatLine(leftColumnSize, "", "")
+	msg += formatLine(leftColumnSize, "", "INVALID VALUE - the value of this item does not satisfy schema validation:")
+	switch {
+	case e.Sensitive:
+		msg += formatLine(leftColumnSize, "", fmt.Sprintf("     found: %s", RedactedValue))
+	case !isNode(e.Value):
+		msg += formatLine(leftColumnSize, "", fmt.Sprintf("     found: %v", e.Value))
+	}
+	msg += formatLine(leftColumnSize, "", fmt.Sprintf("  expected: %s (by %s)", e.Expected, e.DefinitionPosition.AsCompactString()))
+	// predicates may include the value in their failure message
+	if e.Cause != nil && !e.Sensitive {
+		msg += formatLine(leftColumnSize, "", fmt.Sprintf("  (error: %s)", e.Cause))
+	}
+
+	return msg
+}
+
+type invalidStringValueError struct {
+	Found              yamlmeta.Node
+	Value              string
+	Expected           string
+	DefinitionPosition *filepos.Position
+	Sensitive          bool
+}
+
+func (e invalidStringValueError) Error() string {
+	position := e.Found.GetPosition().AsCompactString()
+	leftColumnSize := len(position) + 1
+	lineContent := lineContentOf(e.Found.GetPosition(), e.Sensitive)
+
+	found := fmt.Sprintf("%q", e.Value)
+	if e.Sensitive {
+		found = RedactedValue
+	}
+
+	msg := "\n"
+	msg += formatLine(leftColumnSize, position, lineContent)
+	msg += formatLine(leftColumnSize, "", "")
+	msg += formatLine(leftColumnSize, "", "INVALID VALUE - the string could not be converted to the type schema expected:")
+	msg += formatLine(leftColumnSize, "", fmt.Sprintf("     found: %s", found))
+	msg += formatLine(leftColumnSize, "", fmt.Sprintf("  expected: %s (by %s)", e.Expected, e.DefinitionPosition.AsCompactString()))
+	return msg
+}
+
+type invalidValuesError struct {
+	Violations []error
+}
+
+func (e invalidValuesError) Error() string {
+	msg := ""
+	for _, err := range e.Violations {
+		msg += err.Error() + "\n"
+	}
+	return msg
+}
+
+type missingValue struct {
+	Path       string
+	Definition *filepos.Position
+}
+
+type missingRequiredValuesError struct {
+	Missing []missingValue
+}
+
+func (e missingRequiredValuesError) Error() string {
+	msg := "\n"
+	msg += fmt.Sprintf("MISSING REQUIRED VALUES - %d data value(s) marked with @%s were not provided:\n", len(e.Missing), AnnotationSchemaRequired)
+	for _, missing := range e.Missing {
+		msg += fmt.Sprintf("  - %s (required by %s)\n", missing.Path, missing.Definition.AsCompactString())
+	}
+	msg += "  (hint: provide values via data values files or flags, e.g. --data-value-yaml key=value)\n"
+	return msg
+}
+
+// sensitiveViolation is a violation that may show the value of the item it was found at
+type sensitiveViolation interface {
+	foundAt() *filepos.Position
+	markSensitive()
+}
+
+func (t *mismatchedTypeError) foundAt() *filepos.Position     { return t.Found.GetPosition() }
+func (t *mismatchedTypeError) markSensitive()                 { t.Sensitive = true }
+func (t *unionMismatchError) foundAt() *filepos.Position      { return t.Found.GetPosition() }
+func (t *unionMismatchError) markSensitive()                  { t.Sensitive = true }
+func (e *invalidValueError) foundAt() *filepos.Position       { return e.Found.GetPosition() }
+func (e *invalidValueError) markSensitive()                   { e.Sensitive = true }
+func (e *invalidStringValueError) foundAt() *filepos.Position { return e.Found.GetPosition() }
+func (e *invalidStringValueError) markSensitive()             { e.Sensitive = true }
+
+// MarkSensitiveViolations makes violations found at sensitive items of node (or within them)
+// not show their values (i.e. neither the value itself nor the line it was given on)
+func MarkSensitiveViolations(node yamlmeta.Node, chk yamlmeta.TypeCheck) yamlmeta.TypeCheck {
+	if !chk.HasViolations() {
+		return chk
+	}
+	// scalar values are checked as nodes of their own, hence violations are matched by position
+	sensitivePositions := map[*filepos.Position]bool{}
+	collectSensitivePositions(node, false, sensitivePositions)
+
+	for _, violation := range chk.Violations {
+		if typedViolation, ok := violation.(sensitiveViolation); ok && sensitivePositions[typedViolation.foundAt()] {
+			typedViolation.markSensitive()
+		}
+	}
+	return chk
+}
+
+func collectSensitivePositions(node yamlmeta.Node, sensitive bool, result map[*filepos.Position]bool) {
+	if mapItem, ok := node.(*yamlmeta.MapItem); ok {
+		if itemType, ok := mapItem.Type.(*MapItemType); ok && itemType.IsSensitive() {
+			sensitive = true
+		}
+	}
+	if sensitive {
+		result[node.GetPosition()] = true
+	}
+
+	for _, val := range node.GetValues() {
+		if childNode, ok := val.(yamlmeta.Node); ok {
+			collectSensitivePositions(childNode, sensitive, result)
+		}
+	}
+}
+
+// lineContentOf returns line at position, unless it may show a sensitive value
+func lineContentOf(position *filepos.Position, sensitive bool) string {
+	if sensitive {
+		return ""
+	}
+	return position.GetLine()
+}
+
+func isNode(value interface{}) bool {
+	_, ok := value.(yamlmeta.Node)
+	return ok
+}
+
 func leftPadding(size int) string {
 	result := ""
 	for i := 0; i < size; i++ {
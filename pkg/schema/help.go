@@ -0,0 +1,76 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package schema
+
+import (
+	"github.com/k14s/ytt/pkg/filepos"
+	"github.com/k14s/ytt/pkg/yamlmeta"
+)
+
+// ValueHelp describes a single data value (as defined by schema) for end users of a library
+type ValueHelp struct {
+	Path        string
+	Type        string
+	Default     string // empty for required and sensitive values
+	Nullable    bool
+	Required    bool
+	Sensitive   bool // set for values nested within sensitive values as well
+	Description string
+	Deprecated  *string // notice (possibly empty) if value is deprecated
+	Position    *filepos.Position
+}
+
+// Help lists every data value defined by schema, in order of definition.
+// Maps are listed only if they are documented (or nullable), since their items describe them.
+func Help(docType *DocumentType) []ValueHelp {
+	mapType, ok := docType.ValueType.(*MapType)
+	if !ok {
+		return nil
+	}
+	return helpOfMap("", mapType, false)
+}
+
+func helpOfMap(path string, mapType *MapType, sensitive bool) []ValueHelp {
+	var result []ValueHelp
+
+	for _, item := range mapType.Items {
+		itemPath := mapItemPath(path, item.Key)
+		itemSensitive := sensitive || item.IsSensitive()
+
+		_, isMap := item.ValueType.(*MapType)
+		if !isMap || item.IsNullable() || len(item.Description()) > 0 {
+			help := ValueHelp{
+				Path:        itemPath,
+				Type:        typeName(item.ValueType),
+				Nullable:    item.IsNullable(),
+				Required:    item.IsRequired(),
+				Sensitive:   itemSensitive,
+				Description: item.Description(),
+				Position:    item.Position,
+			}
+			if !isMap && !help.Required && !help.Sensitive {
+				help.Default = defaultDesc(item)
+			}
+			if notice, deprecated := item.Deprecated(); deprecated {
+				help.Deprecated = &notice
+			}
+			result = append(result, help)
+		}
+
+		result = append(result, helpOfValueType(itemPath, item.ValueType, itemSensitive)...)
+	}
+
+	return result
+}
+
+// helpOfValueType lists values nested within collections
+func helpOfValueType(path string, valueType yamlmeta.Type, sensitive bool) []ValueHelp {
+	switch typedType := valueType.(type) {
+	case *MapType:
+		return helpOfMap(path, typedType, sensitive)
+	case *ArrayType:
+		return helpOfValueType(path+"[]", typedType.ItemsType.GetValueType(), sensitive)
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package schema
+
+import (
+	"fmt"
+
+	"github.com/k14s/ytt/pkg/filepos"
+	"github.com/k14s/ytt/pkg/yamlmeta"
+)
+
+type inferredKind int
+
+const (
+	inferredKindUnknown inferredKind = iota // only nulls were seen
+	inferredKindScalar
+	inferredKindMap
+	inferredKindArray
+	inferredKindAny
+)
+
+// inferredShape accumulates the shape of a value across data values documents
+type inferredShape struct {
+	kind     inferredKind
+	nullable bool
+
+	// value as first seen (i.e. from the earliest document) is used for values of any type
+	defaultSet bool
+	defaultVal interface{}
+	// first non-null scalar determines scalar type
+	sample interface{}
+
+	mapItems  []*inferredMapItem
+	itemShape *inferredShape
+}
+
+type inferredMapItem struct {
+	key   interface{}
+	shape *inferredShape
+}
+
+// InferDocument builds a schema document that accepts all of the given data values documents.
+// Shapes of all documents are merged; scalars found first become defaults (arrays default to empty
+// as usual, since data values documents are expected to be used along with the schema).
+func InferDocument(valuesDocs []*yamlmeta.Document) (*yamlmeta.Document, error) {
+	root := &inferredShape{}
+
+	for _, doc := range valuesDocs {
+		switch doc.Value.(type) {
+		case *yamlmeta.Map:
+			root.merge(doc.Value)
+		case nil:
+			// empty document has no data values
+		default:
+			return nil, fmt.Errorf("Expected data values document (%s) to be a map", doc.Position.AsCompactString())
+		}
+	}
+
+	schemaValue := root.asMap()
+	if root.kind == inferredKindUnknown {
+		schemaValue = &yamlmeta.Map{Position: filepos.NewUnknownPosition()}
+	}
+
+	return &yamlmeta.Document{
+		Metas:    []*yamlmeta.Meta{newInferredMeta("@schema/match data_values=True")},
+		Value:    schemaValue,
+		Position: filepos.NewUnknownPosition(),
+	}, nil
+}
+
+func (s *inferredShape) merge(value interface{}) {
+	if !s.defaultSet {
+		s.defaultSet = true
+		s.defaultVal = yamlmeta.NewGoFromAST(value)
+	}
+
+	switch typedValue := value.(type) {
+	case nil:
+		s.nullable = true
+
+	case *yamlmeta.Map:
+		if !s.becomes(inferredKindMap) {
+			return
+		}
+		for _, item := range typedValue.Items {
+			s.mapItemShape(item.Key).merge(item.Value)
+		}
+
+	case *yamlmeta.Array:
+		if !s.becomes(inferredKindArray) {
+			return
+		}
+		if s.itemShape == nil {
+			s.itemShape = &inferredShape{}
+		}
+		for _, item := range typedValue.Items {
+			s.itemShape.merge(item.Value)
+		}
+
+	case string, int, bool:
+		if !s.becomes(inferredKindScalar) {
+			return
+		}
+		if s.sample == nil {
+			s.sample = value
+		} else if fmt.Sprintf("%T", s.sample) != fmt.Sprintf("%T", value) {
+			s.kind = inferredKindAny
+		}
+
+	default:
+		// values of types that schema does not support (e.g. floats) are not type checked
+		s.kind = inferredKindAny
+	}
+}
+
+// becomes changes kind of the shape (if not yet known) and returns false if shape is of a different kind
+func (s *inferredShape) becomes(kind inferredKind) bool {
+	switch s.kind {
+	case inferredKindUnknown:
+		s.kind = kind
+		return true
+	case kind:
+		return true
+	default:
+		s.kind = inferredKindAny
+		return false
+	}
+}
+
+func (s *inferredShape) mapItemShape(key interface{}) *inferredShape {
+	for _, item := range s.mapItems {
+		if item.key == key {
+			return item.shape
+		}
+	}
+	item := &inferredMapItem{key: key, shape: &inferredShape{}}
+	s.mapItems = append(s.mapItems, item)
+	return item.shape
+}
+
+func (s *inferredShape) asMap() *yamlmeta.Map {
+	result := &yamlmeta.Map{Position: filepos.NewUnknownPosition()}
+	for _, item := range s.mapItems {
+		result.Items = append(result.Items, item.shape.asMapItem(item.key))
+	}
+	return result
+}
+
+func (s *inferredShape) asMapItem(key interface{}) *yamlmeta.MapItem {
+	item := &yamlmeta.MapItem{Key: key, Position: filepos.NewUnknownPosition()}
+
+	switch s.kind {
+	case inferredKindUnknown, inferredKindAny:
+		item.Metas = append(item.Metas, newInferredMeta("@%s %s=True", AnnotationSchemaType, TypeAnnotationKwargAny))
+		item.Value = yamlmeta.NewASTFromInterface(s.defaultVal)
+		return item
+
+	case inferredKindScalar:
+		item.Value = s.sample
+
+	case inferredKindMap:
+		item.Value = s.asMap()
+
+	case inferredKindArray:
+		item.Value = s.asArray()
+	}
+
+	if s.nullable {
+		item.Metas = append(item.Metas, newInferredMeta("@%s", AnnotationSchemaNullable))
+	}
+
+	return item
+}
+
+func (s *inferredShape) asArray() *yamlmeta.Array {
+	item := &yamlmeta.ArrayItem{Position: filepos.NewUnknownPosition()}
+	itemShape := s.itemShape
+
+	switch {
+	case itemShape == nil || itemShape.nullable ||
+		itemShape.kind == inferredKindUnknown || itemShape.kind == inferredKindAny:
+		// array items cannot be nullable, hence allow any value
+		item.Metas = append(item.Metas, newInferredMeta("@%s %s=True", AnnotationSchemaType, TypeAnnotationKwargAny))
+		if itemShape != nil && itemShape.kind == inferredKindAny {
+			item.Value = yamlmeta.NewASTFromInterface(itemShape.defaultVal)
+		}
+	case itemShape.kind == inferredKindScalar:
+		item.Value = itemShape.sample
+	case itemShape.kind == inferredKindMap:
+		item.Value = itemShape.asMap()
+	case itemShape.kind == inferredKindArray:
+		item.Value = itemShape.asArray()
+	}
+
+	return &yamlmeta.Array{Items: []*yamlmeta.ArrayItem{item}, Position: filepos.NewUnknownPosition()}
+}
+
+func newInferredMeta(format string, args ...interface{}) *yamlmeta.Meta {
+	return &yamlmeta.Meta{Data: fmt.Sprintf(format, args...), Position: filepos.NewUnknownPosition()}
+}
@@ -0,0 +1,524 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package schema
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/k14s/ytt/pkg/template"
+	"github.com/k14s/ytt/pkg/template/core"
+	"github.com/k14s/ytt/pkg/yamlmeta"
+)
+
+const (
+	jsonSchemaTypeObject  = "object"
+	jsonSchemaTypeArray   = "array"
+	jsonSchemaTypeString  = "string"
+	jsonSchemaTypeInteger = "integer"
+	jsonSchemaTypeBoolean = "boolean"
+	jsonSchemaTypeNumber  = "number"
+	jsonSchemaTypeNull    = "null"
+)
+
+// jsonSchemaUnionTypes maps JSON Schema types to Starlark builtins accepted by @schema/type one_of
+var jsonSchemaUnionTypes = map[string]string{
+	jsonSchemaTypeObject:  "dict",
+	jsonSchemaTypeArray:   "list",
+	jsonSchemaTypeString:  "str",
+	jsonSchemaTypeInteger: "int",
+	jsonSchemaTypeBoolean: "bool",
+}
+
+// jsonSchemaValidations maps JSON Schema validation keywords to @schema/validation keyword arguments
+var jsonSchemaValidations = []struct {
+	keyword string
+	kwarg   string
+}{
+	{"enum", ValidationKwargOneOf},
+	{"minimum", ValidationKwargMin},
+	{"maximum", ValidationKwargMax},
+	{"minLength", ValidationKwargMinLen},
+	{"maxLength", ValidationKwargMaxLen},
+	{"minItems", ValidationKwargMinLen},
+	{"maxItems", ValidationKwargMaxLen},
+}
+
+// NewDocumentFromJSONSchema converts a JSON Schema (or OpenAPI v3) document into
+// a schema document, as if it was written in YAML with @schema/... annotations.
+// For OpenAPI documents, "dataValues" schema (or the only schema) in components is used.
+func NewDocumentFromJSONSchema(doc *yamlmeta.Document) (*yamlmeta.Document, error) {
+	root, ok := doc.Value.(*yamlmeta.Map)
+	if !ok {
+		return nil, NewInvalidSchemaError(doc, "expected JSON Schema document to be a map", "")
+	}
+
+	converter := &jsonSchemaConverter{root: root}
+
+	rootSchema := root
+	if _, isOpenAPI := mapValue(root, "openapi"); isOpenAPI {
+		var err error
+		rootSchema, err = converter.openAPIDataValuesSchema()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rootSchema, err := converter.resolve(rootSchema)
+	if err != nil {
+		return nil, err
+	}
+	defer converter.doneResolving()
+
+	typeNames, _, err := converter.typeNamesOf(rootSchema)
+	if err != nil {
+		return nil, err
+	}
+	if len(typeNames) != 1 || typeNames[0] != jsonSchemaTypeObject {
+		return nil, NewInvalidSchemaError(rootSchema,
+			fmt.Sprintf("expected root JSON Schema to be of type '%s', but was '%s'", jsonSchemaTypeObject, strings.Join(typeNames, ", ")),
+			"data values are always a map")
+	}
+
+	value, err := converter.mapOf(rootSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	return &yamlmeta.Document{Value: value, Position: doc.Position}, nil
+}
+
+type jsonSchemaConverter struct {
+	root *yamlmeta.Map
+	// schemas (reached via $ref) currently being converted; used to detect recursion
+	resolving []*yamlmeta.Map
+}
+
+func (c *jsonSchemaConverter) openAPIDataValuesSchema() (*yamlmeta.Map, error) {
+	schemas, err := c.resolvePointer([]string{"components", "schemas"}, c.root)
+	if err != nil {
+		return nil, err
+	}
+
+	if dataValues, found := mapValue(schemas, "dataValues"); found {
+		return asSchemaMap(dataValues, schemas)
+	}
+	if len(schemas.Items) == 1 {
+		return asSchemaMap(schemas.Items[0].Value, schemas.Items[0])
+	}
+
+	var names []string
+	for _, item := range schemas.Items {
+		names = append(names, fmt.Sprintf("%v", item.Key))
+	}
+	return nil, NewInvalidSchemaError(schemas,
+		fmt.Sprintf("expected OpenAPI document to have a single schema or a schema named 'dataValues', but found: %s", strings.Join(names, ", ")),
+		"rename the schema describing data values to 'dataValues'")
+}
+
+func (c *jsonSchemaConverter) mapOf(schemaMap *yamlmeta.Map) (*yamlmeta.Map, error) {
+	result := &yamlmeta.Map{Position: schemaMap.Position}
+
+	required := map[interface{}]bool{}
+	if requiredVal, found := mapValue(schemaMap, "required"); found {
+		requiredArray, ok := requiredVal.(*yamlmeta.Array)
+		if !ok {
+			return nil, NewInvalidSchemaError(schemaMap, "expected JSON Schema keyword 'required' to be a list of property names", "")
+		}
+		for _, item := range requiredArray.Items {
+			required[item.Value] = true
+		}
+	}
+
+	propertiesVal, found := mapValue(schemaMap, "properties")
+	if !found {
+		return result, nil
+	}
+	properties, ok := propertiesVal.(*yamlmeta.Map)
+	if !ok {
+		return nil, NewInvalidSchemaError(schemaMap, "expected JSON Schema keyword 'properties' to be a map", "")
+	}
+
+	for _, property := range properties.Items {
+		item, err := c.mapItemOf(property, required[property.Key])
+		if err != nil {
+			return nil, err
+		}
+		result.Items = append(result.Items, item)
+	}
+	return result, nil
+}
+
+func (c *jsonSchemaConverter) mapItemOf(property *yamlmeta.MapItem, required bool) (*yamlmeta.MapItem, error) {
+	propertySchema, err := asSchemaMap(property.Value, property)
+	if err != nil {
+		return nil, err
+	}
+	propertySchema, err = c.resolve(propertySchema)
+	if err != nil {
+		return nil, err
+	}
+	defer c.doneResolving()
+
+	value, anns, nullable, err := c.valueOf(propertySchema)
+	if err != nil {
+		return nil, err
+	}
+
+	defaultVal, hasDefault := mapValue(propertySchema, "default")
+	switch {
+	case hasDefault && defaultVal == nil:
+		nullable = true
+	case hasDefault:
+		if anns.Has(AnnotationSchemaType) {
+			// values of any type are their own default
+			value = defaultVal
+		} else {
+			anns[AnnotationSchemaDefault] = template.NodeAnnotation{
+				Args: starlark.Tuple{core.NewGoValue(yamlmeta.NewGoFromAST(defaultVal)).AsStarlarkValue()},
+			}
+		}
+	case !required:
+		// optional scalars without a default are left unset
+		switch value.(type) {
+		case *yamlmeta.Map, *yamlmeta.Array:
+		default:
+			nullable = true
+		}
+	case !nullable:
+		// required scalars without a default have to be provided via data values
+		// (required maps and arrays are made of their items' defaults)
+		switch value.(type) {
+		case *yamlmeta.Map, *yamlmeta.Array:
+		default:
+			anns[AnnotationSchemaRequired] = template.NodeAnnotation{}
+		}
+	}
+
+	if nullable {
+		anns[AnnotationSchemaNullable] = template.NodeAnnotation{}
+	}
+
+	err = c.addDocAnnotations(propertySchema, anns)
+	if err != nil {
+		return nil, err
+	}
+
+	item := &yamlmeta.MapItem{Key: property.Key, Value: value, Position: property.Position}
+	item.SetAnnotations(anns)
+	return item, nil
+}
+
+func (c *jsonSchemaConverter) arrayOf(schemaMap *yamlmeta.Map) (*yamlmeta.Array, error) {
+	var item *yamlmeta.ArrayItem
+
+	itemsVal, found := mapValue(schemaMap, "items")
+	if found {
+		itemsSchema, err := asSchemaMap(itemsVal, schemaMap)
+		if err != nil {
+			return nil, err
+		}
+		itemsSchema, err = c.resolve(itemsSchema)
+		if err != nil {
+			return nil, err
+		}
+		defer c.doneResolving()
+
+		value, anns, nullable, err := c.valueOf(itemsSchema)
+		if err != nil {
+			return nil, err
+		}
+		if nullable {
+			return nil, NewInvalidSchemaError(itemsSchema, "nullable array items are not supported", "")
+		}
+		item = &yamlmeta.ArrayItem{Value: value, Position: itemsSchema.Position}
+		item.SetAnnotations(anns)
+	} else {
+		item = &yamlmeta.ArrayItem{Position: schemaMap.Position}
+		item.SetAnnotations(anyTypeAnnotations())
+	}
+
+	return &yamlmeta.Array{Items: []*yamlmeta.ArrayItem{item}, Position: schemaMap.Position}, nil
+}
+
+// valueOf returns a value (along with annotations) that declares the same type as given JSON Schema
+func (c *jsonSchemaConverter) valueOf(schemaMap *yamlmeta.Map) (interface{}, template.NodeAnnotations, bool, error) {
+	typeNames, nullable, err := c.typeNamesOf(schemaMap)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	var value interface{}
+	var anns template.NodeAnnotations
+
+	switch len(typeNames) {
+	case 0:
+		anns = anyTypeAnnotations()
+	case 1:
+		anns = template.NodeAnnotations{}
+		value, err = c.valueOfType(schemaMap, typeNames[0])
+	default:
+		value, anns, err = c.unionOf(schemaMap, typeNames)
+	}
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	var kwargs []starlark.Tuple
+	for _, validation := range jsonSchemaValidations {
+		if val, found := mapValue(schemaMap, validation.keyword); found {
+			kwarg := starlark.Tuple{starlark.String(validation.kwarg), core.NewGoValue(yamlmeta.NewGoFromAST(val)).AsStarlarkValue()}
+			kwargs = append(kwargs, kwarg)
+		}
+	}
+	if len(kwargs) > 0 {
+		anns[AnnotationSchemaValidation] = template.NodeAnnotation{Kwargs: kwargs}
+	}
+
+	return value, anns, nullable, nil
+}
+
+func (c *jsonSchemaConverter) addDocAnnotations(schemaMap *yamlmeta.Map, anns template.NodeAnnotations) error {
+	if desc, found := mapValue(schemaMap, "description"); found {
+		descStr, ok := desc.(string)
+		if !ok {
+			return NewInvalidSchemaError(schemaMap, "expected JSON Schema keyword 'description' to be a string", "")
+		}
+		anns[AnnotationSchemaDesc] = template.NodeAnnotation{Args: starlark.Tuple{starlark.String(descStr)}}
+	}
+	if deprecated, found := mapValue(schemaMap, "deprecated"); found && deprecated == true {
+		anns[AnnotationSchemaDeprecated] = template.NodeAnnotation{}
+	}
+	return nil
+}
+
+// valueOfType returns a value that declares given (single) JSON Schema type
+func (c *jsonSchemaConverter) valueOfType(schemaMap *yamlmeta.Map, typeName string) (interface{}, error) {
+	switch typeName {
+	case jsonSchemaTypeObject:
+		return c.mapOf(schemaMap)
+	case jsonSchemaTypeArray:
+		return c.arrayOf(schemaMap)
+	case jsonSchemaTypeString:
+		return "", nil
+	case jsonSchemaTypeInteger:
+		return 0, nil
+	case jsonSchemaTypeBoolean:
+		return false, nil
+	case jsonSchemaTypeNumber:
+		return nil, c.unsupportedTypeError(schemaMap, typeName)
+	default:
+		return nil, NewInvalidSchemaError(schemaMap, fmt.Sprintf("unknown JSON Schema type '%s'", typeName), "")
+	}
+}
+
+func (c *jsonSchemaConverter) unsupportedTypeError(schemaMap *yamlmeta.Map, typeName string) error {
+	return NewInvalidSchemaError(schemaMap,
+		fmt.Sprintf("JSON Schema type '%s' is not supported", typeName), "use type 'integer' instead")
+}
+
+// unionOf returns a value (along with @schema/type one_of annotation) that allows any of given JSON Schema types.
+// Objects and arrays within a union are not further described, hence allow any contents.
+func (c *jsonSchemaConverter) unionOf(schemaMap *yamlmeta.Map, typeNames []string) (interface{}, template.NodeAnnotations, error) {
+	var alternatives []starlark.Value
+	var value interface{}
+
+	for i, typeName := range typeNames {
+		if typeName == jsonSchemaTypeNumber {
+			return nil, nil, c.unsupportedTypeError(schemaMap, typeName)
+		}
+		builtinName, found := jsonSchemaUnionTypes[typeName]
+		if !found {
+			return nil, nil, NewInvalidSchemaError(schemaMap, fmt.Sprintf("unknown JSON Schema type '%s'", typeName), "")
+		}
+		alternatives = append(alternatives, starlark.Universe[builtinName])
+
+		if i == 0 {
+			switch typeName {
+			case jsonSchemaTypeObject:
+				value = &yamlmeta.Map{Position: schemaMap.Position}
+			case jsonSchemaTypeArray:
+				value = &yamlmeta.Array{Position: schemaMap.Position}
+			default:
+				value = unionScalarTypes[builtinName]
+			}
+		}
+	}
+
+	anns := template.NodeAnnotations{
+		AnnotationSchemaType: template.NodeAnnotation{
+			Kwargs: []starlark.Tuple{{starlark.String(TypeAnnotationKwargOneOf), starlark.NewList(alternatives)}},
+		},
+	}
+	return value, anns, nil
+}
+
+// typeNamesOf determines type names (none if any type is allowed) and whether null is allowed
+func (c *jsonSchemaConverter) typeNamesOf(schemaMap *yamlmeta.Map) ([]string, bool, error) {
+	nullable := false
+	if nullableVal, found := mapValue(schemaMap, "nullable"); found && nullableVal == true {
+		nullable = true
+	}
+
+	typeVal, found := mapValue(schemaMap, "type")
+	if !found {
+		if typeName := c.inferredTypeOf(schemaMap); typeName != "" {
+			return []string{typeName}, nullable, nil
+		}
+		return nil, nullable, nil
+	}
+
+	switch typedVal := typeVal.(type) {
+	case string:
+		return []string{typedVal}, nullable, nil
+	case *yamlmeta.Array:
+		var typeNames []string
+		for _, item := range typedVal.Items {
+			typeName, ok := item.Value.(string)
+			if !ok {
+				return nil, false, NewInvalidSchemaError(schemaMap, "expected JSON Schema keyword 'type' to be a list of strings", "")
+			}
+			if typeName == jsonSchemaTypeNull {
+				nullable = true
+			} else {
+				typeNames = append(typeNames, typeName)
+			}
+		}
+		return typeNames, nullable, nil
+	default:
+		return nil, false, NewInvalidSchemaError(schemaMap, "expected JSON Schema keyword 'type' to be a string or a list of strings", "")
+	}
+}
+
+func (c *jsonSchemaConverter) inferredTypeOf(schemaMap *yamlmeta.Map) string {
+	if _, found := mapValue(schemaMap, "properties"); found {
+		return jsonSchemaTypeObject
+	}
+	if _, found := mapValue(schemaMap, "items"); found {
+		return jsonSchemaTypeArray
+	}
+
+	var samples []interface{}
+	if enum, found := mapValue(schemaMap, "enum"); found {
+		if enumArray, ok := enum.(*yamlmeta.Array); ok {
+			for _, item := range enumArray.Items {
+				samples = append(samples, item.Value)
+			}
+		}
+	}
+	if defaultVal, found := mapValue(schemaMap, "default"); found {
+		samples = append(samples, defaultVal)
+	}
+
+	for _, sample := range samples {
+		switch sample.(type) {
+		case string:
+			return jsonSchemaTypeString
+		case int:
+			return jsonSchemaTypeInteger
+		case float64:
+			return jsonSchemaTypeNumber
+		case bool:
+			return jsonSchemaTypeBoolean
+		}
+	}
+	// includes composite schemas (i.e. allOf, anyOf, oneOf)
+	return ""
+}
+
+// resolve follows $ref (within the same document) until a schema without $ref is found.
+// Each resolved schema must be released via doneResolving once converted.
+func (c *jsonSchemaConverter) resolve(schemaMap *yamlmeta.Map) (*yamlmeta.Map, error) {
+	// references may point at each other (or themselves) without ever reaching a schema
+	followedRefs := map[string]bool{}
+
+	for {
+		refVal, found := mapValue(schemaMap, "$ref")
+		if !found {
+			break
+		}
+		ref, ok := refVal.(string)
+		if !ok || !strings.HasPrefix(ref, "#") {
+			return nil, NewInvalidSchemaError(schemaMap,
+				fmt.Sprintf("unsupported JSON Schema reference '%v'", refVal),
+				"only references within the same document (e.g. '#/definitions/port') are supported")
+		}
+		if followedRefs[ref] {
+			return nil, NewInvalidSchemaError(schemaMap, fmt.Sprintf("circular $ref '%s'", ref),
+				"reference must eventually resolve to a schema without $ref")
+		}
+		followedRefs[ref] = true
+
+		var path []string
+		for _, token := range strings.Split(strings.TrimPrefix(ref, "#"), "/")[1:] {
+			path = append(path, strings.NewReplacer("~1", "/", "~0", "~").Replace(token))
+		}
+
+		var err error
+		schemaMap, err = c.resolvePointer(path, schemaMap)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, resolving := range c.resolving {
+		if resolving == schemaMap {
+			return nil, NewInvalidSchemaError(schemaMap, "recursive JSON Schema references are not supported",
+				"data values schema must have a finite depth")
+		}
+	}
+	c.resolving = append(c.resolving, schemaMap)
+
+	return schemaMap, nil
+}
+
+func (c *jsonSchemaConverter) doneResolving() {
+	c.resolving = c.resolving[:len(c.resolving)-1]
+}
+
+func (c *jsonSchemaConverter) resolvePointer(path []string, referrer yamlmeta.Node) (*yamlmeta.Map, error) {
+	var current interface{} = c.root
+	for i, key := range path {
+		currentMap, ok := current.(*yamlmeta.Map)
+		if ok {
+			current, ok = mapValue(currentMap, key)
+		}
+		if !ok {
+			return nil, NewInvalidSchemaError(referrer,
+				fmt.Sprintf("expected JSON Schema document to have '#/%s'", strings.Join(path[:i+1], "/")), "")
+		}
+	}
+	return asSchemaMap(current, referrer)
+}
+
+func anyTypeAnnotations() template.NodeAnnotations {
+	return template.NodeAnnotations{
+		AnnotationSchemaType: template.NodeAnnotation{
+			Kwargs: []starlark.Tuple{{starlark.String(TypeAnnotationKwargAny), starlark.Bool(true)}},
+		},
+	}
+}
+
+func asSchemaMap(val interface{}, referrer yamlmeta.Node) (*yamlmeta.Map, error) {
+	switch typedVal := val.(type) {
+	case *yamlmeta.Map:
+		return typedVal, nil
+	case bool:
+		if typedVal {
+			// 'true' schema allows any value
+			return &yamlmeta.Map{Position: referrer.GetPosition()}, nil
+		}
+	}
+	return nil, NewInvalidSchemaError(referrer, fmt.Sprintf("expected JSON Schema to be a map, but was %T", val), "")
+}
+
+func mapValue(m *yamlmeta.Map, key string) (interface{}, bool) {
+	for _, item := range m.Items {
+		if item.Key == key {
+			return item.Value, true
+		}
+	}
+	return nil, false
+}
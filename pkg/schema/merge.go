@@ -0,0 +1,90 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package schema
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/k14s/ytt/pkg/structmeta"
+	"github.com/k14s/ytt/pkg/template"
+	"github.com/k14s/ytt/pkg/template/core"
+	"github.com/k14s/ytt/pkg/yamlmeta"
+)
+
+const (
+	AnnotationSchemaMerge structmeta.AnnotationName = "schema/merge"
+
+	ArrayMergeReplace    = "replace"
+	ArrayMergeAppend     = "append"
+	ArrayMergeByKey      = "merge-by-key"
+	arrayMergeByKeyInfix = ":"
+)
+
+// ArrayMergeStrategy describes how an array given by a data values
+// overlay is combined with an array set by previous data values
+type ArrayMergeStrategy struct {
+	Op  string
+	Key string // only set for merge-by-key
+}
+
+// NewArrayMergeStrategy parses strategy given in one of forms:
+// 'replace', 'append' or 'merge-by-key:<field>'
+func NewArrayMergeStrategy(str string) (ArrayMergeStrategy, error) {
+	switch {
+	case str == ArrayMergeReplace || str == ArrayMergeAppend:
+		return ArrayMergeStrategy{Op: str}, nil
+
+	case strings.HasPrefix(str, ArrayMergeByKey+arrayMergeByKeyInfix):
+		key := strings.TrimPrefix(str, ArrayMergeByKey+arrayMergeByKeyInfix)
+		if len(key) == 0 {
+			return ArrayMergeStrategy{}, fmt.Errorf("Expected array merge strategy '%s' to specify field name", str)
+		}
+		return ArrayMergeStrategy{Op: ArrayMergeByKey, Key: key}, nil
+
+	default:
+		return ArrayMergeStrategy{}, fmt.Errorf("Expected array merge strategy to be one of '%s', '%s' or '%s%s<field>', but was '%s'",
+			ArrayMergeReplace, ArrayMergeAppend, ArrayMergeByKey, arrayMergeByKeyInfix, str)
+	}
+}
+
+// IsSet indicates whether strategy was specified (zero value means default overlay behaviour)
+func (s ArrayMergeStrategy) IsSet() bool { return len(s.Op) > 0 }
+
+func (s ArrayMergeStrategy) String() string {
+	if s.Op == ArrayMergeByKey {
+		return s.Op + arrayMergeByKeyInfix + s.Key
+	}
+	return s.Op
+}
+
+// NewMergeAnnotation returns unset strategy when node is not annotated with @schema/merge
+func NewMergeAnnotation(node yamlmeta.Node) (ArrayMergeStrategy, error) {
+	anns := template.NewAnnotations(node)
+	if !anns.Has(AnnotationSchemaMerge) {
+		return ArrayMergeStrategy{}, nil
+	}
+
+	hint := fmt.Sprintf("specify one of '%s', '%s' or '%s%s<field>' (e.g. @schema/merge \"%s%sname\")",
+		ArrayMergeReplace, ArrayMergeAppend, ArrayMergeByKey, arrayMergeByKeyInfix, ArrayMergeByKey, arrayMergeByKeyInfix)
+
+	args := anns.Args(AnnotationSchemaMerge)
+	if args.Len() != 1 || len(anns.Kwargs(AnnotationSchemaMerge)) > 0 {
+		return ArrayMergeStrategy{}, NewInvalidSchemaError(node,
+			fmt.Sprintf("expected @%s annotation to have exactly one argument", AnnotationSchemaMerge), hint)
+	}
+
+	str, err := core.NewStarlarkValue(args.Index(0)).AsString()
+	if err != nil {
+		return ArrayMergeStrategy{}, NewInvalidSchemaError(node,
+			fmt.Sprintf("expected @%s annotation argument to be a string", AnnotationSchemaMerge), hint)
+	}
+
+	strategy, err := NewArrayMergeStrategy(str)
+	if err != nil {
+		return ArrayMergeStrategy{}, NewInvalidSchemaError(node,
+			fmt.Sprintf("invalid @%s annotation: %s", AnnotationSchemaMerge, err), hint)
+	}
+	return strategy, nil
+}
@@ -0,0 +1,192 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package schema
+
+import (
+	"github.com/k14s/ytt/pkg/orderedmap"
+	"github.com/k14s/ytt/pkg/template/core"
+	"github.com/k14s/ytt/pkg/yamlmeta"
+)
+
+const (
+	exportedSchemaTitle = "Schema for data values, generated by ytt"
+	jsonSchemaDialect   = "https://json-schema.org/draft/2019-09/schema"
+)
+
+// OpenAPIDocument describes data values (as typed by schema) as an OpenAPI v3 document
+type OpenAPIDocument struct {
+	docType *DocumentType
+}
+
+// JSONSchemaDocument describes data values (as typed by schema) as a JSON Schema document
+type JSONSchemaDocument struct {
+	docType *DocumentType
+}
+
+func NewOpenAPIDocument(docType *DocumentType) *OpenAPIDocument {
+	return &OpenAPIDocument{docType}
+}
+
+func NewJSONSchemaDocument(docType *DocumentType) *JSONSchemaDocument {
+	return &JSONSchemaDocument{docType}
+}
+
+func (o *OpenAPIDocument) AsDocument() *yamlmeta.Document {
+	info := orderedmap.NewMap()
+	info.Set("version", "0.1.0")
+	info.Set("title", exportedSchemaTitle)
+
+	schemas := orderedmap.NewMap()
+	schemas.Set("dataValues", schemaExporter{openAPI: true}.valueSchema(o.docType.ValueType))
+
+	components := orderedmap.NewMap()
+	components.Set("schemas", schemas)
+
+	doc := orderedmap.NewMap()
+	doc.Set("openapi", "3.0.0")
+	doc.Set("info", info)
+	doc.Set("paths", orderedmap.NewMap())
+	doc.Set("components", components)
+
+	return &yamlmeta.Document{Value: yamlmeta.NewASTFromInterface(doc)}
+}
+
+func (j *JSONSchemaDocument) AsDocument() *yamlmeta.Document {
+	doc := orderedmap.NewMap()
+	doc.Set("$schema", jsonSchemaDialect)
+	doc.Set("title", exportedSchemaTitle)
+
+	schemaExporter{}.valueSchema(j.docType.ValueType).Iterate(func(k, v interface{}) {
+		doc.Set(k, v)
+	})
+
+	return &yamlmeta.Document{Value: yamlmeta.NewASTFromInterface(doc)}
+}
+
+// schemaExporter converts schema types into (OpenAPI flavored, if requested) JSON Schema objects
+type schemaExporter struct {
+	openAPI bool
+}
+
+func (e schemaExporter) valueSchema(valueType yamlmeta.Type) *orderedmap.Map {
+	result := orderedmap.NewMap()
+
+	switch typedType := valueType.(type) {
+	case *MapType:
+		properties := orderedmap.NewMap()
+		var required []interface{}
+		for _, item := range typedType.Items {
+			properties.Set(item.Key, e.mapItemSchema(item))
+			if item.IsRequired() {
+				required = append(required, item.Key)
+			}
+		}
+		result.Set("type", "object")
+		result.Set("additionalProperties", false)
+		result.Set("properties", properties)
+		if len(required) > 0 {
+			result.Set("required", required)
+		}
+
+	case *ArrayType:
+		result.Set("type", "array")
+		result.Set("items", e.valueSchema(typedType.ItemsType.GetValueType()))
+
+	case *ScalarType:
+		result.Set("type", typedType.String())
+
+	case *UnionType:
+		var values []interface{}
+		for _, value := range typedType.Values {
+			values = append(values, core.NewStarlarkValue(value).AsGoValue())
+		}
+		var alternatives []interface{}
+		for _, scalarType := range typedType.ScalarTypes {
+			alternatives = append(alternatives, e.valueSchema(scalarType))
+		}
+		if typedType.AllowsMap {
+			alternatives = append(alternatives, e.typeSchema("object"))
+		}
+		if typedType.AllowsArray {
+			alternatives = append(alternatives, e.typeSchema("array"))
+		}
+		switch {
+		case len(alternatives) == 0:
+			result.Set("enum", values)
+		case len(values) > 0:
+			enum := orderedmap.NewMap()
+			enum.Set("enum", values)
+			result.Set("anyOf", append(alternatives, enum))
+		default:
+			result.Set("anyOf", alternatives)
+		}
+
+	case *AnyType, nil:
+		// any value is allowed; JSON Schema expresses that as the absence of constraints
+	}
+	return result
+}
+
+func (e schemaExporter) typeSchema(typeName string) *orderedmap.Map {
+	result := orderedmap.NewMap()
+	result.Set("type", typeName)
+	return result
+}
+
+func (e schemaExporter) mapItemSchema(item *MapItemType) *orderedmap.Map {
+	valueSchema := e.valueSchema(item.ValueType)
+	result := orderedmap.NewMap()
+
+	typeName, typed := valueSchema.Get("type")
+	if typed {
+		result.Set("type", typeName)
+	}
+
+	if item.IsNullable() || item.IsAny() || item.ValueType == nil {
+		if e.openAPI {
+			result.Set("nullable", true)
+		} else if typed {
+			result.Set("type", []interface{}{typeName, "null"})
+		} else if alternatives, isUnion := valueSchema.Get("anyOf"); isUnion {
+			valueSchema.Set("anyOf", append(alternatives.([]interface{}), e.typeSchema("null")))
+		} else if values, isEnum := valueSchema.Get("enum"); isEnum {
+			valueSchema.Set("enum", append(values.([]interface{}), nil))
+		}
+	}
+
+	if desc := item.Description(); len(desc) > 0 {
+		result.Set("description", desc)
+	}
+
+	if examples := item.Examples(); len(examples) > 0 {
+		if e.openAPI {
+			// OpenAPI v3 allows only a single example per schema object
+			result.Set("x-example-description", examples[0].Description)
+			result.Set("example", yamlmeta.NewGoFromAST(examples[0].Value))
+		} else {
+			var values []interface{}
+			for _, example := range examples {
+				values = append(values, yamlmeta.NewGoFromAST(example.Value))
+			}
+			result.Set("examples", values)
+		}
+	}
+
+	if _, deprecated := item.Deprecated(); deprecated {
+		result.Set("deprecated", true)
+	}
+
+	valueSchema.Iterate(func(k, v interface{}) {
+		if k != "type" {
+			result.Set(k, v)
+		}
+	})
+
+	// defaults of maps are already described by defaults of their items (and required values have none)
+	if _, isMap := item.ValueType.(*MapType); !isMap && !item.IsRequired() {
+		result.Set("default", yamlmeta.NewGoFromAST(item.DefaultValue))
+	}
+
+	return result
+}
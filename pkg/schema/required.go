@@ -0,0 +1,78 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package schema
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/k14s/ytt/pkg/structmeta"
+	"github.com/k14s/ytt/pkg/template"
+	"github.com/k14s/ytt/pkg/yamlmeta"
+)
+
+// checkRequired verifies that @schema/required is used on an item that can be left without a default
+func checkRequired(item *yamlmeta.MapItem, itemType *MapItemType) error {
+	anns := template.NewAnnotations(item)
+
+	if anns.Args(AnnotationSchemaRequired).Len() > 0 || len(anns.Kwargs(AnnotationSchemaRequired)) > 0 {
+		return NewInvalidSchemaError(item,
+			fmt.Sprintf("expected @%s annotation to not have arguments", AnnotationSchemaRequired), "")
+	}
+
+	for _, conflicting := range []structmeta.AnnotationName{AnnotationSchemaNullable, AnnotationSchemaDefault} {
+		if anns.Has(conflicting) {
+			return NewInvalidSchemaError(item,
+				fmt.Sprintf("@%s and @%s annotations cannot be used together", AnnotationSchemaRequired, conflicting),
+				"required values have no default (they must be provided via data values)")
+		}
+	}
+
+	switch itemType.ValueType.(type) {
+	case *MapType, *ArrayType:
+		return NewInvalidSchemaError(item,
+			fmt.Sprintf("expected @%s annotation to be on a scalar value, but found %s", AnnotationSchemaRequired, itemType.ValueType.String()),
+			"mark values within the collection as required instead")
+	}
+
+	return nil
+}
+
+// CheckRequiredValues reports (all at once) required values that were not provided
+// (i.e. are still null once all data values were overlaid and defaults filled in)
+func CheckRequiredValues(node yamlmeta.Node) (chk yamlmeta.TypeCheck) {
+	missing := collectMissingValues(node, nil)
+	if len(missing) > 0 {
+		chk.Violations = append(chk.Violations, &missingRequiredValuesError{Missing: missing})
+	}
+	return
+}
+
+func collectMissingValues(node yamlmeta.Node, path []string) []missingValue {
+	var missing []missingValue
+
+	switch typedNode := node.(type) {
+	case *yamlmeta.MapItem:
+		path = append(append([]string{}, path...), fmt.Sprintf("%v", typedNode.Key))
+
+		if itemType, ok := typedNode.Type.(*MapItemType); ok && itemType.IsRequired() && typedNode.Value == nil {
+			return []missingValue{{Path: strings.Join(path, "."), Definition: itemType.Position}}
+		}
+
+	case *yamlmeta.Array:
+		for i, item := range typedNode.Items {
+			if childNode, ok := item.Value.(yamlmeta.Node); ok {
+				missing = append(missing, collectMissingValues(childNode, append(path, fmt.Sprintf("%d", i)))...)
+			}
+		}
+		return missing
+	}
+
+	for _, val := range node.GetValues() {
+		if childNode, ok := val.(yamlmeta.Node); ok {
+			missing = append(missing, collectMissingValues(childNode, path)...)
+		}
+	}
+	return missing
+}
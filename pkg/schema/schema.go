@@ -6,9 +6,12 @@ package schema
 import (
 	"fmt"
 
+	"github.com/k14s/starlark-go/starlark"
 	"github.com/k14s/ytt/pkg/filepos"
+	"github.com/k14s/ytt/pkg/structmeta"
 	"github.com/k14s/ytt/pkg/template"
 	"github.com/k14s/ytt/pkg/yamlmeta"
+	yttoverlay "github.com/k14s/ytt/pkg/yttlibrary/overlay"
 )
 
 type AnySchema struct {
@@ -30,15 +33,10 @@ func NewDocumentSchema(doc *yamlmeta.Document) (*DocumentSchema, error) {
 		return nil, err
 	}
 
-	schemaDVs, err := defaultDataValues(doc)
-	if err != nil {
-		return nil, err
-	}
-
 	return &DocumentSchema{
 		Name:       "dataValues",
 		Source:     doc,
-		defaultDVs: schemaDVs,
+		defaultDVs: docType.defaultDataValues(),
 		Allowed:    docType,
 	}, nil
 }
@@ -82,14 +80,27 @@ func NewMapType(m *yamlmeta.Map) (*MapType, error) {
 }
 
 func NewMapItemType(item *yamlmeta.MapItem) (*MapItemType, error) {
-	valueType, err := newCollectionItemValueType(item.Value, item.Position)
+	typeAnn, err := NewTypeAnnotation(item)
 	if err != nil {
 		return nil, err
 	}
 
-	defaultValue := item.Value
-	if _, ok := item.Value.(*yamlmeta.Array); ok {
-		defaultValue = &yamlmeta.Array{}
+	var valueType yamlmeta.Type
+	var defaultValue interface{}
+
+	switch {
+	case typeAnn.Any:
+		valueType = &AnyType{Position: item.Position}
+		defaultValue = item.Value
+	case typeAnn.OneOf != nil:
+		valueType = typeAnn.OneOf
+		defaultValue = item.Value
+	default:
+		valueType, err = newCollectionItemValueType(item.Value, item.Position)
+		if err != nil {
+			return nil, err
+		}
+		defaultValue = defaultValueOf(valueType, item.Value)
 	}
 
 	templateAnnotations := template.NewAnnotations(item)
@@ -105,7 +116,95 @@ func NewMapItemType(item *yamlmeta.MapItem) (*MapItemType, error) {
 		annotations[key] = val
 	}
 
-	return &MapItemType{Key: item.Key, ValueType: valueType, DefaultValue: defaultValue, Position: item.Position, Annotations: annotations}, nil
+	mapItemType := &MapItemType{Key: item.Key, ValueType: valueType, DefaultValue: defaultValue, Position: item.Position, Annotations: annotations}
+
+	if mapItemType.IsRequired() {
+		err := checkRequired(item, mapItemType)
+		if err != nil {
+			return nil, err
+		}
+		// value in schema only describes the type
+		mapItemType.DefaultValue = nil
+		defaultValue = nil
+	}
+
+	if typeAnn.OneOf != nil && defaultValue != nil {
+		err := checkUnionDefault(item, typeAnn.OneOf)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	defaultAnn, err := NewDefaultAnnotation(item)
+	if err != nil {
+		return nil, err
+	}
+	if defaultAnn != nil {
+		mapItemType.DefaultValue, err = mapItemType.checkValue(defaultAnn.Value, AnnotationSchemaDefault)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	mergeStrategy, err := NewMergeAnnotation(item)
+	if err != nil {
+		return nil, err
+	}
+	if mergeStrategy.IsSet() {
+		if _, isArray := valueType.(*ArrayType); !isArray {
+			return nil, NewInvalidSchemaError(item,
+				fmt.Sprintf("expected @%s annotation to be on an array, but found %s", AnnotationSchemaMerge, valueType.String()),
+				"merge strategies only apply to arrays (maps are always merged key by key)")
+		}
+		mapItemType.Annotations[AnnotationSchemaMerge] = mergeStrategy
+	}
+
+	err = mapItemType.processDocAnnotations(item)
+	if err != nil {
+		return nil, err
+	}
+
+	mapItemType.Validation, err = NewValidationAnnotation(item)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapItemType, nil
+}
+
+// processDocAnnotations records annotations that document (rather than type) an item
+func (t *MapItemType) processDocAnnotations(item *yamlmeta.MapItem) error {
+	desc, err := NewDescAnnotation(item)
+	if err != nil {
+		return err
+	}
+	if len(desc) > 0 {
+		t.Annotations[AnnotationSchemaDesc] = desc
+	}
+
+	examples, err := NewExamplesAnnotation(item)
+	if err != nil {
+		return err
+	}
+	if len(examples) > 0 {
+		for i, example := range examples {
+			examples[i].Value, err = t.checkValue(example.Value, AnnotationSchemaExamples)
+			if err != nil {
+				return err
+			}
+		}
+		t.Annotations[AnnotationSchemaExamples] = examples
+	}
+
+	notice, deprecated, err := NewDeprecatedAnnotation(item)
+	if err != nil {
+		return err
+	}
+	if deprecated {
+		t.Annotations[AnnotationSchemaDeprecated] = notice
+	}
+
+	return nil
 }
 
 func NewArrayType(a *yamlmeta.Array) (*ArrayType, error) {
@@ -116,7 +215,7 @@ func NewArrayType(a *yamlmeta.Array) (*ArrayType, error) {
 	}
 	if len(a.Items) > 1 {
 		// assumption: the user wants to supply defaults and (incorrectly) assumed they should go in schema
-		return nil, NewInvalidArrayDefinitionError(a, "to add elements to the default value of an array (i.e. an empty list), declare them in a @data/values document")
+		return nil, NewInvalidArrayDefinitionError(a, "to add elements to the default value of an array (i.e. an empty list), declare them in a @data/values document or with @schema/default")
 	}
 
 	arrayItemType, err := NewArrayItemType(a.Items[0])
@@ -128,18 +227,62 @@ func NewArrayType(a *yamlmeta.Array) (*ArrayType, error) {
 }
 
 func NewArrayItemType(item *yamlmeta.ArrayItem) (*ArrayItemType, error) {
-	valueType, err := newCollectionItemValueType(item.Value, item.Position)
+	typeAnn, err := NewTypeAnnotation(item)
 	if err != nil {
 		return nil, err
 	}
 
+	var valueType yamlmeta.Type
+	switch {
+	case typeAnn.Any:
+		valueType = &AnyType{Position: item.Position}
+	case typeAnn.OneOf != nil:
+		valueType = typeAnn.OneOf
+		err := checkUnionDefault(item, typeAnn.OneOf)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		valueType, err = newCollectionItemValueType(item.Value, item.Position)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	annotations := template.NewAnnotations(item)
 
 	if _, found := annotations[AnnotationSchemaNullable]; found {
 		return nil, NewInvalidSchemaError(item, fmt.Sprintf("@%s is not supported on array items", AnnotationSchemaNullable), "")
 	}
 
-	return &ArrayItemType{ValueType: valueType, Position: item.Position}, nil
+	validation, err := NewValidationAnnotation(item)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ArrayItemType{ValueType: valueType, Position: item.Position, Validation: validation}, nil
+}
+
+// checkUnionDefault ensures that value of a schema item is one of the alternatives of its union
+func checkUnionDefault(item yamlmeta.Node, unionType *UnionType) error {
+	value := item.GetValues()[0]
+	switch value.(type) {
+	case *yamlmeta.Map:
+		if unionType.AllowsMap {
+			return nil
+		}
+	case *yamlmeta.Array:
+		if unionType.AllowsArray {
+			return nil
+		}
+	default:
+		if unionType.allows(value) {
+			return nil
+		}
+	}
+	return NewInvalidSchemaError(item,
+		fmt.Sprintf("value of this item is not one of the alternatives given via @%s", AnnotationSchemaType),
+		fmt.Sprintf("set value to %s", unionType.String()))
 }
 
 func newCollectionItemValueType(collectionItemValue interface{}, position *filepos.Position) (yamlmeta.Type, error) {
@@ -169,30 +312,125 @@ func newCollectionItemValueType(collectionItemValue interface{}, position *filep
 	return nil, fmt.Errorf("Collection item type did not match any known types")
 }
 
-func defaultDataValues(doc *yamlmeta.Document) (*yamlmeta.Document, error) {
-	docCopy := doc.DeepCopyAsNode()
-	for _, value := range docCopy.GetValues() {
-		if valueAsANode, ok := value.(yamlmeta.Node); ok {
-			setDefaultValues(valueAsANode)
+// defaultValueOf determines the value a data value has when none is given:
+// arrays are empty, maps hold the defaults of their items, scalars are as written in schema.
+func defaultValueOf(valueType yamlmeta.Type, value interface{}) interface{} {
+	switch typedValueType := valueType.(type) {
+	case *MapType:
+		return typedValueType.defaultValue()
+	case *ArrayType:
+		return &yamlmeta.Array{Position: typedValueType.Position}
+	default:
+		return value
+	}
+}
+
+func (m *MapType) defaultValue() *yamlmeta.Map {
+	result := &yamlmeta.Map{Position: m.Position}
+	for _, item := range m.Items {
+		result.Items = append(result.Items, &yamlmeta.MapItem{
+			Key:      item.Key,
+			Value:    copyOfDefault(item.DefaultValue),
+			Position: item.Position,
+		})
+	}
+	return result
+}
+
+func (t *DocumentType) defaultDataValues() *yamlmeta.Document {
+	var value interface{}
+	if t.ValueType != nil {
+		value = defaultValueOf(t.ValueType, nil)
+	}
+	return &yamlmeta.Document{Value: value, Position: t.Position}
+}
+
+// FillInDefaults adds, with their default values, items missing from maps typed by a schema
+// (i.e. after type has been assigned)
+func FillInDefaults(node yamlmeta.Node) (chk yamlmeta.TypeCheck) {
+	if mapNode, ok := node.(*yamlmeta.Map); ok {
+		if mapType, ok := mapNode.Type.(*MapType); ok {
+			chk = mapType.fillInDefaults(mapNode)
 		}
 	}
 
-	return docCopy.(*yamlmeta.Document), nil
+	for _, val := range node.GetValues() {
+		if childNode, ok := val.(yamlmeta.Node); ok {
+			childCheck := FillInDefaults(childNode)
+			chk.Violations = append(chk.Violations, childCheck.Violations...)
+		}
+	}
+	return
 }
 
-func setDefaultValues(node yamlmeta.Node) {
-	switch typedNode := node.(type) {
-	case *yamlmeta.Map:
-		for _, value := range typedNode.Items {
-			setDefaultValues(value)
+// NullOutRemovedValues turns removal of items (e.g. via --data-value-unset) into setting
+// them to null, since data values always include every item defined by schema;
+// type check then reports items that are not nullable
+func NullOutRemovedValues(node yamlmeta.Node) (chk yamlmeta.TypeCheck) {
+	if mapItem, ok := node.(*yamlmeta.MapItem); ok {
+		anns := template.NewAnnotations(mapItem)
+		if _, isTyped := mapItem.Type.(*MapItemType); isTyped && anns.Has(yttoverlay.AnnotationRemove) {
+			delete(anns, yttoverlay.AnnotationRemove)
+			anns[yttoverlay.AnnotationReplace] = template.NodeAnnotation{}
+			mapItem.SetAnnotations(anns)
+			mapItem.Value = nil
+			return
 		}
-	case *yamlmeta.MapItem:
-		if valueAsANode, ok := typedNode.Value.(yamlmeta.Node); ok {
-			setDefaultValues(valueAsANode)
+	}
+
+	for _, val := range node.GetValues() {
+		if childNode, ok := val.(yamlmeta.Node); ok {
+			childCheck := NullOutRemovedValues(childNode)
+			chk.Violations = append(chk.Violations, childCheck.Violations...)
 		}
-	case *yamlmeta.Array:
-		typedNode.Items = []*yamlmeta.ArrayItem{}
 	}
+	return
+}
+
+// ResetToDefaults sets values of items that replace previous values
+// (e.g. via --data-value-default) to their defaults as given by schema
+func ResetToDefaults(node yamlmeta.Node) (chk yamlmeta.TypeCheck) {
+	if mapItem, ok := node.(*yamlmeta.MapItem); ok {
+		itemType, isTyped := mapItem.Type.(*MapItemType)
+		if isTyped && template.NewAnnotations(mapItem).Has(yttoverlay.AnnotationReplace) {
+			mapItem.Value = copyOfDefault(itemType.DefaultValue)
+			return itemType.AssignTypeTo(mapItem)
+		}
+	}
+
+	for _, val := range node.GetValues() {
+		if childNode, ok := val.(yamlmeta.Node); ok {
+			childCheck := ResetToDefaults(childNode)
+			chk.Violations = append(chk.Violations, childCheck.Violations...)
+		}
+	}
+	return
+}
+
+// checkValue ensures that value given via an annotation conforms to this item's type
+func (t *MapItemType) checkValue(value interface{}, annName structmeta.AnnotationName) (interface{}, error) {
+	item := &yamlmeta.MapItem{Key: t.Key, Value: value, Position: t.Position}
+
+	chk := t.AssignTypeTo(item)
+	if !chk.HasViolations() {
+		chk = FillInDefaults(item)
+	}
+	if !chk.HasViolations() {
+		chk = item.Check()
+	}
+	if chk.HasViolations() {
+		return nil, fmt.Errorf("Invalid @%s value for '%v' (by %s):%s",
+			annName, t.Key, t.Position.AsCompactString(), chk.Error())
+	}
+
+	return item.Value, nil
+}
+
+func copyOfDefault(value interface{}) interface{} {
+	if node, ok := value.(yamlmeta.Node); ok {
+		return node.DeepCopyAsInterface()
+	}
+	return value
 }
 
 func (as *AnySchema) AssignType(typeable yamlmeta.Typeable) yamlmeta.TypeCheck {
@@ -233,3 +471,20 @@ func (n NullSchema) ValidateWithValues(valuesFilesCount int) error {
 func (s *DocumentSchema) ValidateWithValues(valuesFilesCount int) error {
 	return nil
 }
+
+func (as *AnySchema) Validate(_ *yamlmeta.Document) error {
+	return nil
+}
+
+func (n NullSchema) Validate(_ *yamlmeta.Document) error {
+	return nil
+}
+
+// Validate checks values against all @schema/validation rules, reporting every violation
+func (s *DocumentSchema) Validate(values *yamlmeta.Document) error {
+	violations := ValidateValues(values, &starlark.Thread{Name: "data-values-validation"})
+	if len(violations) > 0 {
+		return NewInvalidValuesError(violations)
+	}
+	return nil
+}
@@ -5,7 +5,9 @@ package schema
 
 import (
 	"fmt"
+	"strings"
 
+	"github.com/k14s/starlark-go/starlark"
 	"github.com/k14s/ytt/pkg/filepos"
 	"github.com/k14s/ytt/pkg/structmeta"
 	"github.com/k14s/ytt/pkg/yamlmeta"
@@ -13,6 +15,7 @@ import (
 
 const (
 	AnnotationSchemaNullable structmeta.AnnotationName = "schema/nullable"
+	AnnotationSchemaRequired structmeta.AnnotationName = "schema/required"
 )
 
 var _ yamlmeta.Type = (*DocumentType)(nil)
@@ -20,6 +23,8 @@ var _ yamlmeta.Type = (*MapType)(nil)
 var _ yamlmeta.Type = (*MapItemType)(nil)
 var _ yamlmeta.Type = (*ArrayType)(nil)
 var _ yamlmeta.Type = (*ArrayItemType)(nil)
+var _ yamlmeta.Type = (*AnyType)(nil)
+var _ yamlmeta.Type = (*UnionType)(nil)
 
 type DocumentType struct {
 	Source    *yamlmeta.Document
@@ -36,19 +41,33 @@ type MapItemType struct {
 	DefaultValue interface{}
 	Position     *filepos.Position
 	Annotations  TypeAnnotations
+	Validation   *Validation
 }
 type ArrayType struct {
 	ItemsType yamlmeta.Type
 	Position  *filepos.Position
 }
 type ArrayItemType struct {
-	ValueType yamlmeta.Type
-	Position  *filepos.Position
+	ValueType  yamlmeta.Type
+	Position   *filepos.Position
+	Validation *Validation
 }
 type ScalarType struct {
 	Value    interface{}
 	Position *filepos.Position
 }
+type AnyType struct {
+	Position *filepos.Position
+}
+
+// UnionType allows values of any of its types (union) or any of its values (enum), or a mix of both
+type UnionType struct {
+	ScalarTypes []*ScalarType
+	AllowsMap   bool
+	AllowsArray bool
+	Values      []starlark.Value
+	Position    *filepos.Position
+}
 
 type TypeAnnotations map[structmeta.AnnotationName]interface{}
 
@@ -70,6 +89,12 @@ func (a ArrayItemType) GetValueType() yamlmeta.Type {
 func (m ScalarType) GetValueType() yamlmeta.Type {
 	panic("Not implemented because it is unreachable")
 }
+func (a AnyType) GetValueType() yamlmeta.Type {
+	return &a
+}
+func (u UnionType) GetValueType() yamlmeta.Type {
+	panic("Not implemented because it is unreachable")
+}
 
 func (t *DocumentType) PositionOfDefinition() *filepos.Position {
 	return t.Position
@@ -89,6 +114,12 @@ func (a ArrayItemType) PositionOfDefinition() *filepos.Position {
 func (m ScalarType) PositionOfDefinition() *filepos.Position {
 	return m.Position
 }
+func (a AnyType) PositionOfDefinition() *filepos.Position {
+	return a.Position
+}
+func (u UnionType) PositionOfDefinition() *filepos.Position {
+	return u.Position
+}
 
 func (t *DocumentType) String() string {
 	return "document"
@@ -115,6 +146,25 @@ func (m ScalarType) String() string {
 		return fmt.Sprintf("%T", m.Value)
 	}
 }
+func (a AnyType) String() string {
+	return "any"
+}
+func (u UnionType) String() string {
+	var alternatives []string
+	for _, scalarType := range u.ScalarTypes {
+		alternatives = append(alternatives, scalarType.String())
+	}
+	if u.AllowsMap {
+		alternatives = append(alternatives, "map")
+	}
+	if u.AllowsArray {
+		alternatives = append(alternatives, "array")
+	}
+	for _, value := range u.Values {
+		alternatives = append(alternatives, value.String())
+	}
+	return "one of: " + strings.Join(alternatives, ", ")
+}
 
 func (t *DocumentType) CheckType(_ yamlmeta.TypeWithValues) (chk yamlmeta.TypeCheck) {
 	return
@@ -143,7 +193,7 @@ func (t *MapItemType) CheckType(node yamlmeta.TypeWithValues) (chk yamlmeta.Type
 		// A Map must've yielded a non-MapItem which is not valid YAML
 		panic(fmt.Sprintf("MapItem type check was called on a non-MapItem: %#v", node))
 	}
-	if mapItem.Value == nil && !t.IsNullable() {
+	if mapItem.Value == nil && !t.IsNullable() && !t.IsAny() {
 		chk.Violations = append(chk.Violations,
 			NewMismatchedTypeError(mapItem, t))
 	}
@@ -177,7 +227,7 @@ func (m *ScalarType) CheckType(node yamlmeta.TypeWithValues) (chk yamlmeta.TypeC
 			chk.Violations = append(chk.Violations,
 				NewMismatchedTypeError(node, m))
 		}
-	case int:
+	case int, int64, uint64:
 		if _, ok := m.Value.(int); !ok {
 			chk.Violations = append(chk.Violations,
 				NewMismatchedTypeError(node, m))
@@ -194,6 +244,52 @@ func (m *ScalarType) CheckType(node yamlmeta.TypeWithValues) (chk yamlmeta.TypeC
 	return
 }
 
+func (a *AnyType) CheckType(_ yamlmeta.TypeWithValues) (chk yamlmeta.TypeCheck) {
+	return
+}
+
+func (u *UnionType) CheckType(node yamlmeta.TypeWithValues) (chk yamlmeta.TypeCheck) {
+	value := node.GetValues()[0]
+	if !u.allows(value) {
+		chk.Violations = append(chk.Violations, NewUnionMismatchError(node, value, u))
+	}
+	return
+}
+
+// allows indicates whether scalar value is of one of the types, or is one of the values, of the union
+func (u *UnionType) allows(value interface{}) bool {
+	for _, scalarType := range u.ScalarTypes {
+		chk := scalarType.CheckType(&yamlmeta.Scalar{Value: value})
+		if !chk.HasViolations() {
+			return true
+		}
+	}
+	if value == nil {
+		return false
+	}
+	starlarkValue := asStarlarkValue(value)
+	for _, allowedValue := range u.Values {
+		if allowedValue.Type() != starlarkValue.Type() {
+			// e.g. booleans are not equal to numbers; integers are equal to floats
+			if !isStarlarkNumber(allowedValue) || !isStarlarkNumber(starlarkValue) {
+				continue
+			}
+		}
+		if equal, err := starlark.Equal(starlarkValue, allowedValue); err == nil && equal {
+			return true
+		}
+	}
+	return false
+}
+
+func isStarlarkNumber(value starlark.Value) bool {
+	switch value.(type) {
+	case starlark.Int, starlark.Float:
+		return true
+	}
+	return false
+}
+
 func (t *DocumentType) AssignTypeTo(typeable yamlmeta.Typeable) (chk yamlmeta.TypeCheck) {
 	doc, ok := typeable.(*yamlmeta.Document)
 	if !ok {
@@ -237,24 +333,25 @@ func (m *MapType) AssignTypeTo(typeable yamlmeta.Typeable) (chk yamlmeta.TypeChe
 		chk.Violations = append(chk.Violations, NewMismatchedTypeError(typeable, m))
 		return
 	}
-	var foundKeys []interface{}
 	typeable.SetType(m)
 	for _, mapItem := range mapNode.Items {
 		for _, itemType := range m.Items {
 			if mapItem.Key == itemType.Key {
-				foundKeys = append(foundKeys, itemType.Key)
 				childCheck := itemType.AssignTypeTo(mapItem)
 				chk.Violations = append(chk.Violations, childCheck.Violations...)
 				break
 			}
 		}
 	}
-
-	m.applySchemaDefaults(foundKeys, chk, mapNode)
 	return
 }
 
-func (m *MapType) applySchemaDefaults(foundKeys []interface{}, chk yamlmeta.TypeCheck, mapNode *yamlmeta.Map) {
+func (m *MapType) fillInDefaults(mapNode *yamlmeta.Map) (chk yamlmeta.TypeCheck) {
+	var foundKeys []interface{}
+	for _, mapItem := range mapNode.Items {
+		foundKeys = append(foundKeys, mapItem.Key)
+	}
+
 	for _, item := range m.Items {
 		if contains(foundKeys, item.Key) {
 			continue
@@ -262,7 +359,7 @@ func (m *MapType) applySchemaDefaults(foundKeys []interface{}, chk yamlmeta.Type
 
 		val := &yamlmeta.MapItem{
 			Key:      item.Key,
-			Value:    item.DefaultValue,
+			Value:    copyOfDefault(item.DefaultValue),
 			Position: item.Position,
 		}
 		childCheck := item.AssignTypeTo(val)
@@ -272,6 +369,7 @@ func (m *MapType) applySchemaDefaults(foundKeys []interface{}, chk yamlmeta.Type
 			panic(fmt.Sprintf("Internal inconsistency: adding map item: %s", err))
 		}
 	}
+	return
 }
 
 func contains(haystack []interface{}, needle interface{}) bool {
@@ -329,6 +427,33 @@ func (m *ScalarType) AssignTypeTo(typeable yamlmeta.Typeable) (chk yamlmeta.Type
 	panic(fmt.Sprintf("Attempt to assign a type to a scalar. (scalars are not nodes in the AST) m=%#v ; typeable=%#v", m, typeable))
 }
 
+func (u *UnionType) AssignTypeTo(typeable yamlmeta.Typeable) (chk yamlmeta.TypeCheck) {
+	switch typeable.(type) {
+	case *yamlmeta.Map:
+		if u.AllowsMap {
+			// contents of maps (and arrays) within unions are not typed
+			return (&AnyType{Position: u.Position}).AssignTypeTo(typeable)
+		}
+	case *yamlmeta.Array:
+		if u.AllowsArray {
+			return (&AnyType{Position: u.Position}).AssignTypeTo(typeable)
+		}
+	}
+	chk.Violations = append(chk.Violations, NewUnionMismatchError(typeable, typeable, u))
+	return
+}
+
+func (a *AnyType) AssignTypeTo(typeable yamlmeta.Typeable) (chk yamlmeta.TypeCheck) {
+	typeable.SetType(a)
+	for _, value := range typeable.GetValues() {
+		if typeableValue, ok := value.(yamlmeta.Typeable); ok {
+			childCheck := a.AssignTypeTo(typeableValue)
+			chk.Violations = append(chk.Violations, childCheck.Violations...)
+		} // else, at a leaf
+	}
+	return
+}
+
 func (m *MapType) AllowsKey(key interface{}) bool {
 	for _, item := range m.Items {
 		if item.Key == key {
@@ -342,3 +467,46 @@ func (t MapItemType) IsNullable() bool {
 	_, found := t.Annotations[AnnotationSchemaNullable]
 	return found
 }
+
+// IsRequired indicates that value has no default, hence must be provided by data values
+func (t MapItemType) IsRequired() bool {
+	_, found := t.Annotations[AnnotationSchemaRequired]
+	return found
+}
+
+func (t MapItemType) Description() string {
+	desc, _ := t.Annotations[AnnotationSchemaDesc].(string)
+	return desc
+}
+
+func (t MapItemType) Examples() []Example {
+	examples, _ := t.Annotations[AnnotationSchemaExamples].([]Example)
+	return examples
+}
+
+// Deprecated returns notice given via @schema/deprecated (possibly empty)
+func (t MapItemType) Deprecated() (string, bool) {
+	val, found := t.Annotations[AnnotationSchemaDeprecated]
+	if !found {
+		return "", false
+	}
+	notice, _ := val.(string)
+	return notice, true
+}
+
+// IsSensitive indicates that value (including all of its nested values) should not be shown
+func (t MapItemType) IsSensitive() bool {
+	_, found := t.Annotations[AnnotationSchemaSensitive]
+	return found
+}
+
+// ArrayMerge returns strategy given via @schema/merge (unset if not annotated)
+func (t MapItemType) ArrayMerge() ArrayMergeStrategy {
+	strategy, _ := t.Annotations[AnnotationSchemaMerge].(ArrayMergeStrategy)
+	return strategy
+}
+
+func (t MapItemType) IsAny() bool {
+	_, isAny := t.ValueType.(*AnyType)
+	return isAny
+}
@@ -0,0 +1,346 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package schema
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/k14s/starlark-go/syntax"
+	"github.com/k14s/ytt/pkg/filepos"
+	"github.com/k14s/ytt/pkg/structmeta"
+	"github.com/k14s/ytt/pkg/template"
+	"github.com/k14s/ytt/pkg/template/core"
+	"github.com/k14s/ytt/pkg/yamlmeta"
+)
+
+const (
+	AnnotationSchemaValidation structmeta.AnnotationName = "schema/validation"
+
+	ValidationKwargMin     string = "min"
+	ValidationKwargMax     string = "max"
+	ValidationKwargMinLen  string = "min_len"
+	ValidationKwargMaxLen  string = "max_len"
+	ValidationKwargOneOf   string = "one_of"
+	ValidationKwargFormat  string = "format"
+	ValidationKwargPattern string = "pattern"
+)
+
+var (
+	hostnameRegexp = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)(\.[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$`)
+
+	validationFormats = map[string]func(interface{}) bool{
+		"ip": func(val interface{}) bool {
+			str, ok := val.(string)
+			return ok && net.ParseIP(str) != nil
+		},
+		"port": func(val interface{}) bool {
+			var port int64
+			switch typedVal := val.(type) {
+			case int:
+				port = int64(typedVal)
+			case int64:
+				port = typedVal
+			case string:
+				var err error
+				port, err = strconv.ParseInt(typedVal, 10, 64)
+				if err != nil {
+					return false
+				}
+			default:
+				return false
+			}
+			return port > 0 && port <= 65535
+		},
+		"hostname": func(val interface{}) bool {
+			str, ok := val.(string)
+			return ok && len(str) <= 253 && hostnameRegexp.MatchString(str)
+		},
+		"duration": func(val interface{}) bool {
+			str, ok := val.(string)
+			if !ok {
+				return false
+			}
+			_, err := time.ParseDuration(str)
+			return err == nil
+		},
+	}
+)
+
+// Validation holds rules (given via @schema/validation) that a data value must satisfy
+type Validation struct {
+	rules    []validationRule
+	position *filepos.Position
+}
+
+type validationRule struct {
+	desc  string
+	check func(value interface{}, thread *starlark.Thread) (bool, error)
+}
+
+// NewValidationAnnotation returns nil when node is not annotated with @schema/validation
+func NewValidationAnnotation(node yamlmeta.Node) (*Validation, error) {
+	anns := template.NewAnnotations(node)
+	if !anns.Has(AnnotationSchemaValidation) {
+		return nil, nil
+	}
+
+	hint := fmt.Sprintf("supported keyword arguments: '%s', '%s', '%s', '%s', '%s', '%s', '%s'; "+
+		"positional arguments are functions that return True for a valid value",
+		ValidationKwargMin, ValidationKwargMax, ValidationKwargMinLen, ValidationKwargMaxLen, ValidationKwargOneOf, ValidationKwargFormat, ValidationKwargPattern)
+
+	args := anns.Args(AnnotationSchemaValidation)
+	kwargs := anns.Kwargs(AnnotationSchemaValidation)
+	if args.Len() == 0 && len(kwargs) == 0 {
+		return nil, NewInvalidSchemaError(node,
+			fmt.Sprintf("expected @%s annotation to have at least one rule", AnnotationSchemaValidation), hint)
+	}
+
+	validation := &Validation{position: node.GetPosition()}
+
+	for _, arg := range args {
+		predicate, ok := arg.(starlark.Callable)
+		if !ok {
+			return nil, NewInvalidSchemaError(node,
+				fmt.Sprintf("expected @%s annotation positional argument to be a function, but was %s", AnnotationSchemaValidation, arg.Type()), hint)
+		}
+		validation.rules = append(validation.rules, newPredicateRule(predicate))
+	}
+
+	for _, kwarg := range kwargs {
+		kwargName := string(kwarg[0].(starlark.String))
+
+		rule, err := newBuiltinRule(kwargName, kwarg[1])
+		if err != nil {
+			return nil, NewInvalidSchemaError(node,
+				fmt.Sprintf("invalid @%s annotation keyword argument '%s': %s", AnnotationSchemaValidation, kwargName, err), hint)
+		}
+		validation.rules = append(validation.rules, rule)
+	}
+
+	return validation, nil
+}
+
+func newPredicateRule(predicate starlark.Callable) validationRule {
+	return validationRule{
+		desc: fmt.Sprintf("a value for which %s() returns True", predicate.Name()),
+		check: func(value interface{}, thread *starlark.Thread) (bool, error) {
+			result, err := starlark.Call(thread, predicate, starlark.Tuple{asStarlarkValue(value)}, nil)
+			if err != nil {
+				return false, err
+			}
+			return bool(result.Truth()), nil
+		},
+	}
+}
+
+func newBuiltinRule(name string, arg starlark.Value) (validationRule, error) {
+	switch name {
+	case ValidationKwargMin, ValidationKwargMax:
+		if _, isNum := arg.(starlark.Int); !isNum {
+			if _, isFloat := arg.(starlark.Float); !isFloat {
+				return validationRule{}, fmt.Errorf("expected a number, but was %s", arg.Type())
+			}
+		}
+		op, desc := syntax.GE, "greater than or equal to"
+		if name == ValidationKwargMax {
+			op, desc = syntax.LE, "less than or equal to"
+		}
+		return validationRule{
+			desc: fmt.Sprintf("a number %s %s", desc, arg.String()),
+			check: func(value interface{}, _ *starlark.Thread) (bool, error) {
+				switch value.(type) {
+				case int, int64, uint64, float64:
+					return starlark.Compare(op, asStarlarkValue(value), arg)
+				default:
+					return false, nil
+				}
+			},
+		}, nil
+
+	case ValidationKwargMinLen, ValidationKwargMaxLen:
+		limit, err := core.NewStarlarkValue(arg).AsInt64()
+		if err != nil {
+			return validationRule{}, fmt.Errorf("expected an integer, but was %s", arg.Type())
+		}
+		desc := "at least"
+		if name == ValidationKwargMaxLen {
+			desc = "at most"
+		}
+		return validationRule{
+			desc: fmt.Sprintf("a length of %s %d", desc, limit),
+			check: func(value interface{}, _ *starlark.Thread) (bool, error) {
+				length, ok := lengthOf(value)
+				if !ok {
+					return false, nil
+				}
+				if name == ValidationKwargMinLen {
+					return length >= limit, nil
+				}
+				return length <= limit, nil
+			},
+		}, nil
+
+	case ValidationKwargOneOf:
+		iterable, ok := arg.(starlark.Iterable)
+		if !ok {
+			return validationRule{}, fmt.Errorf("expected a list of allowed values, but was %s", arg.Type())
+		}
+		var allowed []starlark.Value
+		var allowedStrs []string
+		iter := iterable.Iterate()
+		defer iter.Done()
+		var x starlark.Value
+		for iter.Next(&x) {
+			allowed = append(allowed, x)
+			allowedStrs = append(allowedStrs, x.String())
+		}
+		return validationRule{
+			desc: fmt.Sprintf("one of: %s", strings.Join(allowedStrs, ", ")),
+			check: func(value interface{}, _ *starlark.Thread) (bool, error) {
+				starlarkValue := asStarlarkValue(value)
+				for _, allowedValue := range allowed {
+					equal, err := starlark.Equal(starlarkValue, allowedValue)
+					if err == nil && equal {
+						return true, nil
+					}
+				}
+				return false, nil
+			},
+		}, nil
+
+	case ValidationKwargFormat:
+		format, err := core.NewStarlarkValue(arg).AsString()
+		if err != nil {
+			return validationRule{}, fmt.Errorf("expected a string, but was %s", arg.Type())
+		}
+		formatFunc, found := validationFormats[format]
+		if !found {
+			var known []string
+			for knownFormat := range validationFormats {
+				known = append(known, knownFormat)
+			}
+			sort.Strings(known)
+			return validationRule{}, fmt.Errorf("unknown format '%s' (known formats: %s)", format, strings.Join(known, ", "))
+		}
+		return validationRule{
+			desc: fmt.Sprintf("a value of format '%s'", format),
+			check: func(value interface{}, _ *starlark.Thread) (bool, error) {
+				return formatFunc(value), nil
+			},
+		}, nil
+
+	case ValidationKwargPattern:
+		pattern, err := core.NewStarlarkValue(arg).AsString()
+		if err != nil {
+			return validationRule{}, fmt.Errorf("expected a string, but was %s", arg.Type())
+		}
+		// compiled once, when schema is loaded, so that invalid patterns are reported early
+		patternRegexp, err := regexp.Compile(pattern)
+		if err != nil {
+			return validationRule{}, fmt.Errorf("invalid regular expression: %s", err)
+		}
+		return validationRule{
+			desc: fmt.Sprintf("a string matching regular expression '%s'", pattern),
+			check: func(value interface{}, _ *starlark.Thread) (bool, error) {
+				str, ok := value.(string)
+				return ok && patternRegexp.MatchString(str), nil
+			},
+		}, nil
+
+	default:
+		return validationRule{}, fmt.Errorf("unknown rule")
+	}
+}
+
+// ruleDescs returns descriptions of rules (none if there is no validation)
+func (v *Validation) ruleDescs() []string {
+	if v == nil {
+		return nil
+	}
+	var result []string
+	for _, rule := range v.rules {
+		result = append(result, rule.desc)
+	}
+	return result
+}
+
+// Validate returns an error for each rule that value does not satisfy
+func (v *Validation) Validate(node yamlmeta.Node, value interface{}, thread *starlark.Thread) []error {
+	return v.validate(node, value, thread, false)
+}
+
+func (v *Validation) validate(node yamlmeta.Node, value interface{}, thread *starlark.Thread, sensitive bool) []error {
+	var violations []error
+
+	// absence of a value is handled by schema types (i.e. @schema/nullable)
+	if value == nil {
+		return nil
+	}
+
+	goValue := yamlmeta.NewGoFromAST(value)
+
+	for _, rule := range v.rules {
+		ok, err := rule.check(goValue, thread)
+		if err != nil || !ok {
+			violations = append(violations, &invalidValueError{Found: node, Value: value, Expected: rule.desc,
+				DefinitionPosition: v.position, Cause: err, Sensitive: sensitive})
+		}
+	}
+	return violations
+}
+
+// ValidateValues runs validations of all items (within node) typed by a schema
+func ValidateValues(node yamlmeta.Node, thread *starlark.Thread) []error {
+	return validateValues(node, thread, false)
+}
+
+func validateValues(node yamlmeta.Node, thread *starlark.Thread, sensitive bool) []error {
+	var violations []error
+
+	switch typedNode := node.(type) {
+	case *yamlmeta.MapItem:
+		if itemType, ok := typedNode.Type.(*MapItemType); ok {
+			sensitive = sensitive || itemType.IsSensitive()
+			if itemType.Validation != nil {
+				violations = append(violations, itemType.Validation.validate(typedNode, typedNode.Value, thread, sensitive)...)
+			}
+		}
+	case *yamlmeta.ArrayItem:
+		if itemType, ok := typedNode.Type.(*ArrayItemType); ok && itemType.Validation != nil {
+			violations = append(violations, itemType.Validation.validate(typedNode, typedNode.Value, thread, sensitive)...)
+		}
+	}
+
+	for _, val := range node.GetValues() {
+		if childNode, ok := val.(yamlmeta.Node); ok {
+			violations = append(violations, validateValues(childNode, thread, sensitive)...)
+		}
+	}
+	return violations
+}
+
+func asStarlarkValue(goValue interface{}) starlark.Value {
+	return core.NewGoValueWithOpts(goValue, core.GoValueOpts{MapIsStruct: true}).AsStarlarkValue()
+}
+
+func lengthOf(value interface{}) (int64, bool) {
+	switch typedValue := value.(type) {
+	case string:
+		return int64(utf8.RuneCountInString(typedValue)), true
+	case []interface{}:
+		return int64(len(typedValue)), true
+	case interface{ Len() int }:
+		return int64(typedValue.Len()), true
+	default:
+		return 0, false
+	}
+}
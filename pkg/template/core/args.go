@@ -19,3 +19,16 @@ func BoolArg(kwargs []starlark.Tuple, keyToFind string) (bool, error) {
 	}
 	return false, nil
 }
+
+func Int64Arg(kwargs []starlark.Tuple, keyToFind string) (int64, error) {
+	for _, arg := range kwargs {
+		key, err := NewStarlarkValue(arg.Index(0)).AsString()
+		if err != nil {
+			return 0, err
+		}
+		if key == keyToFind {
+			return NewStarlarkValue(arg.Index(1)).AsInt64()
+		}
+	}
+	return 0, nil
+}
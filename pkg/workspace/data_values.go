@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/k14s/ytt/pkg/schema"
 	"github.com/k14s/ytt/pkg/template"
 	"github.com/k14s/ytt/pkg/template/core"
 	"github.com/k14s/ytt/pkg/yamlmeta"
@@ -48,7 +49,19 @@ func (p LibRefPiece) AsString() string {
 type DataValues struct {
 	Doc         *yamlmeta.Document
 	AfterLibMod bool
-	used        bool
+	Sensitive   *SensitiveValues
+	Provenance  *Provenance
+	// values were given as strings (e.g. via command line flags),
+	// hence are converted to types expected by schema before type check
+	FromStrings bool
+	// values are to be removed (e.g. via --data-value-unset)
+	Unset bool
+	// values are to be reset to defaults given by schema (e.g. via --data-value-default)
+	ResetToDefaults bool
+	// how arrays are combined with previous values, unless
+	// command line or schema specifies otherwise (e.g. plain files replace arrays)
+	ArrayMerge schema.ArrayMergeStrategy
+	used       bool
 
 	originalLibRef []LibRefPiece
 	libRef         []LibRefPiece
@@ -122,8 +135,9 @@ func (dvd *DataValues) UsedInLibrary(expectedRefPiece LibRefPiece) *DataValues {
 func (dvd *DataValues) deepCopy() *DataValues {
 	var copiedPieces []LibRefPiece
 	copiedPieces = append(copiedPieces, dvd.libRef...)
-	return &DataValues{Doc: dvd.Doc.DeepCopy(), AfterLibMod: dvd.AfterLibMod,
-		libRef: copiedPieces, originalLibRef: dvd.originalLibRef}
+	return &DataValues{Doc: dvd.Doc.DeepCopy(), AfterLibMod: dvd.AfterLibMod, FromStrings: dvd.FromStrings,
+		Unset: dvd.Unset, ResetToDefaults: dvd.ResetToDefaults,
+		ArrayMerge: dvd.ArrayMerge, libRef: copiedPieces, originalLibRef: dvd.originalLibRef}
 }
 
 func parseDVAnnotations(doc *yamlmeta.Document) (bool, []LibRefPiece, bool, error) {
@@ -10,6 +10,7 @@ import (
 	"github.com/k14s/starlark-go/starlark"
 	"github.com/k14s/ytt/pkg/filepos"
 	"github.com/k14s/ytt/pkg/schema"
+	"github.com/k14s/ytt/pkg/template"
 	"github.com/k14s/ytt/pkg/yamlmeta"
 	"github.com/k14s/ytt/pkg/yamltemplate"
 	yttoverlay "github.com/k14s/ytt/pkg/yttlibrary/overlay"
@@ -19,7 +20,10 @@ type DataValuesPreProcessing struct {
 	valuesFiles           []*FileInLibrary
 	valuesOverlays        []*DataValues
 	loader                *TemplateLoader
-	IgnoreUnknownComments bool // TODO remove?
+	sensitive             *SensitiveValues
+	provenance            *Provenance
+	defaultArrays         map[*yamlmeta.Array]bool // set by schema defaults and not yet given by data values
+	IgnoreUnknownComments bool                     // TODO remove?
 }
 
 func (o DataValuesPreProcessing) Apply() (*DataValues, []*DataValues, error) {
@@ -28,17 +32,32 @@ func (o DataValuesPreProcessing) Apply() (*DataValues, []*DataValues, error) {
 	// Respect assigned file order for data values overlaying to succeed
 	SortFilesInLibrary(files)
 
+	o.sensitive = NewSensitiveValues(o.loader.schema)
+	o.provenance = NewProvenance()
+	o.defaultArrays = map[*yamlmeta.Array]bool{}
+
 	dataValues, libraryDataValues, err := o.apply(files)
 	if err != nil {
-		errMsg := "Overlaying data values (in following order: %s): %s"
-		return nil, nil, fmt.Errorf(errMsg, o.allFileDescs(files), err)
+		if fileDescs := o.allFileDescs(files); len(fileDescs) > 0 {
+			err = fmt.Errorf("Overlaying data values (in following order: %s): %s", fileDescs, err)
+		} else {
+			err = fmt.Errorf("Overlaying data values: %s", err)
+		}
+		return nil, nil, o.sensitive.RedactError(err)
 	}
 
+	dataValues.Sensitive = o.sensitive
+	dataValues.Provenance = o.provenance
 	return dataValues, libraryDataValues, nil
 }
 
 func (o DataValuesPreProcessing) apply(files []*FileInLibrary) (*DataValues, []*DataValues, error) {
 	values := o.loader.schema.AsDataValue()
+	o.provenance.CollectDefaults(values)
+	if values != nil {
+		o.collectDefaultArrays(values.Value)
+	}
+
 	var libraryValues []*DataValues
 	for _, fileInLib := range files {
 		valuesDocs, err := o.templateFile(fileInLib)
@@ -47,17 +66,31 @@ func (o DataValuesPreProcessing) apply(files []*FileInLibrary) (*DataValues, []*
 		}
 
 		for _, valuesDoc := range valuesDocs {
+			o.sensitive.Collect(valuesDoc)
+
 			dv, err := NewDataValues(valuesDoc)
 			if err != nil {
 				return nil, nil, err
 			}
 
+			if !dv.HasLib() {
+				// values for other libraries do not need schema of this library
+				err := o.loader.schema.ValidateWithValues(1)
+				if err != nil {
+					return nil, nil, err
+				}
+
+				o.provenance.Collect(valuesDoc)
+			}
+
 			switch {
 			case dv.HasLib():
 				libraryValues = append(libraryValues, dv)
 			case values == nil:
 				values = valuesDoc
 			default:
+				o.applyArrayMerge(dv.Doc, schema.ArrayMergeStrategy{})
+
 				var err error
 				values, err = o.overlay(values, dv.Doc)
 				if err != nil {
@@ -72,6 +105,14 @@ func (o DataValuesPreProcessing) apply(files []*FileInLibrary) (*DataValues, []*
 		return nil, nil, err
 	}
 
+	// includes values set only by schema defaults
+	o.sensitive.Collect(values)
+
+	typeCheck := o.typeCheckValues(values)
+	if typeCheck.HasViolations() {
+		return nil, nil, typeCheck
+	}
+
 	dv, err := NewDataValues(values)
 	if err != nil {
 		return nil, nil, err
@@ -99,21 +140,18 @@ func (o DataValuesPreProcessing) templateFile(fileInLib *FileInLibrary) ([]*yaml
 		return nil, err
 	}
 
-	if _, ok := o.loader.schema.(*schema.AnySchema); !ok {
-		var outerTypeCheck yamlmeta.TypeCheck
-		// Skip first document because the parser inserts a new doc start at the beginning of every doc
-		for _, doc := range resultDocSet.Items[1:] {
-			outerTypeCheck = o.loader.schema.AssignType(doc)
-			if len(outerTypeCheck.Violations) > 0 {
-				return resultDocSet.Items, outerTypeCheck
-			}
-
-			typeCheck := doc.Check()
-			outerTypeCheck.Violations = append(outerTypeCheck.Violations, typeCheck.Violations...)
-		}
-		if len(outerTypeCheck.Violations) > 0 {
-			return resultDocSet.Items, outerTypeCheck
+	var outerTypeCheck yamlmeta.TypeCheck
+	// Skip first document because the parser inserts a new doc start at the beginning of every doc
+	for _, doc := range resultDocSet.Items[1:] {
+		if hasLib, _, _, err := parseDVAnnotations(doc); err != nil || hasLib {
+			// values for other libraries are checked against their own schema (or fail below)
+			continue
 		}
+		typeCheck := o.typeCheckOverlay(doc, false)
+		outerTypeCheck.Violations = append(outerTypeCheck.Violations, typeCheck.Violations...)
+	}
+	if outerTypeCheck.HasViolations() {
+		return nil, outerTypeCheck
 	}
 
 	tplOpts := yamltemplate.MetasOpts{IgnoreUnknown: o.IgnoreUnknownComments}
@@ -137,7 +175,299 @@ func (o DataValuesPreProcessing) templateFile(fileInLib *FileInLibrary) ([]*yaml
 	return valuesDocs, nil
 }
 
+func (o DataValuesPreProcessing) isTyped() bool {
+	_, ok := o.loader.schema.(*schema.DocumentSchema)
+	return ok
+}
+
+// typeCheckOverlay checks a single data values document, before it is overlaid, against schema
+func (o DataValuesPreProcessing) typeCheckOverlay(doc *yamlmeta.Document, coerceStrings bool) yamlmeta.TypeCheck {
+	if !o.isTyped() {
+		return yamlmeta.TypeCheck{}
+	}
+
+	setItems := mapItemsOf(doc)
+
+	typeCheck := o.loader.schema.AssignType(doc)
+	if typeCheck.HasViolations() {
+		return schema.MarkSensitiveViolations(doc, typeCheck)
+	}
+
+	if coerceStrings {
+		typeCheck = schema.CoerceStringValues(doc)
+		if typeCheck.HasViolations() {
+			return schema.MarkSensitiveViolations(doc, typeCheck)
+		}
+	}
+
+	typeCheck = doc.Check()
+	if typeCheck.HasViolations() {
+		return schema.MarkSensitiveViolations(doc, typeCheck)
+	}
+
+	o.warnOnDeprecated(doc, setItems, nil)
+	o.replaceAnyTypedValues(doc)
+
+	return typeCheck
+}
+
+// resetValues sets values that are unset (or reset to defaults) by dv according to schema:
+// unset values are removed, unless schema is present (which requires all of its values),
+// in which case they become null; defaults are only known when schema is present
+func (o DataValuesPreProcessing) resetValues(dv *DataValues) (yamlmeta.TypeCheck, error) {
+	if !dv.Unset && !dv.ResetToDefaults {
+		return yamlmeta.TypeCheck{}, nil
+	}
+
+	if !o.isTyped() {
+		if dv.ResetToDefaults {
+			return yamlmeta.TypeCheck{}, fmt.Errorf("Resetting data value (%s) to its default: Expected schema to provide "+
+				"default, but schema is not present (hint: remove value via --data-value-unset instead)", dv.Doc.Position.AsCompactString())
+		}
+		return yamlmeta.TypeCheck{}, nil
+	}
+
+	typeCheck := o.loader.schema.AssignType(dv.Doc)
+	if typeCheck.HasViolations() {
+		return schema.MarkSensitiveViolations(dv.Doc, typeCheck), nil
+	}
+
+	if dv.Unset {
+		return schema.NullOutRemovedValues(dv.Doc), nil
+	}
+	return schema.ResetToDefaults(dv.Doc), nil
+}
+
+// typeCheckValues checks the final data values (i.e. once all overlays were applied) against schema
+func (o DataValuesPreProcessing) typeCheckValues(doc *yamlmeta.Document) yamlmeta.TypeCheck {
+	if !o.isTyped() {
+		return yamlmeta.TypeCheck{}
+	}
+
+	typeCheck := o.loader.schema.AssignType(doc)
+	if typeCheck.HasViolations() {
+		return schema.MarkSensitiveViolations(doc, typeCheck)
+	}
+
+	// array items added by overlays only include keys that were explicitly set
+	typeCheck = schema.FillInDefaults(doc)
+	if typeCheck.HasViolations() {
+		return typeCheck
+	}
+
+	// reports all missing values at once (rather than type check below reporting them as nulls)
+	typeCheck = schema.CheckRequiredValues(doc)
+	if typeCheck.HasViolations() {
+		return typeCheck
+	}
+
+	return schema.MarkSensitiveViolations(doc, doc.Check())
+}
+
+// warnOnDeprecated reports deprecated items among setItems (i.e. ignoring those filled in by schema)
+func (o DataValuesPreProcessing) warnOnDeprecated(node yamlmeta.Node, setItems map[*yamlmeta.MapItem]bool, path []string) {
+	switch typedNode := node.(type) {
+	case *yamlmeta.MapItem:
+		if !setItems[typedNode] {
+			return
+		}
+		path = append(path, fmt.Sprintf("%v", typedNode.Key))
+
+		if itemType, ok := typedNode.Type.(*schema.MapItemType); ok {
+			if notice, deprecated := itemType.Deprecated(); deprecated {
+				msg := fmt.Sprintf("Warning: data value '%s' (set by %s) is deprecated (by %s)",
+					strings.Join(path, "."), typedNode.Position.AsCompactString(), itemType.Position.AsCompactString())
+				if len(notice) > 0 {
+					msg += ": " + notice
+				}
+				o.loader.ui.Warnf("%s\n", msg)
+			}
+		}
+
+	case *yamlmeta.Array:
+		for i, item := range typedNode.Items {
+			if childNode, ok := item.Value.(yamlmeta.Node); ok {
+				o.warnOnDeprecated(childNode, setItems, append(path, fmt.Sprintf("%d", i)))
+			}
+		}
+		return
+	}
+
+	for _, val := range node.GetValues() {
+		if childNode, ok := val.(yamlmeta.Node); ok {
+			o.warnOnDeprecated(childNode, setItems, path)
+		}
+	}
+}
+
+func mapItemsOf(node yamlmeta.Node) map[*yamlmeta.MapItem]bool {
+	result := map[*yamlmeta.MapItem]bool{}
+	if mapItem, ok := node.(*yamlmeta.MapItem); ok {
+		result[mapItem] = true
+	}
+	for _, val := range node.GetValues() {
+		if childNode, ok := val.(yamlmeta.Node); ok {
+			for item := range mapItemsOf(childNode) {
+				result[item] = true
+			}
+		}
+	}
+	return result
+}
+
+// replaceAnyTypedValues marks values typed as "any" to replace (rather than
+// merge into) previous values since there is no schema to merge them by
+func (o DataValuesPreProcessing) replaceAnyTypedValues(node yamlmeta.Node) {
+	mapItem, ok := node.(*yamlmeta.MapItem)
+	if ok {
+		if itemType, ok := mapItem.Type.(*schema.MapItemType); ok && itemType.IsAny() {
+			if hasOverlayAnnotations(mapItem) {
+				// explicit overlay operation wins
+				return
+			}
+			anns := template.NewAnnotations(mapItem)
+			anns[yttoverlay.AnnotationReplace] = template.NodeAnnotation{}
+			mapItem.SetAnnotations(anns)
+			return
+		}
+	}
+
+	for _, val := range node.GetValues() {
+		if childNode, ok := val.(yamlmeta.Node); ok {
+			o.replaceAnyTypedValues(childNode)
+		}
+	}
+}
+
+// applyArrayMerge annotates arrays of a data values overlay according to merge strategy.
+// Most specific strategy wins: schema, then command line, then source of values.
+// Nodes with explicit overlay annotations are left as is.
+func (o DataValuesPreProcessing) applyArrayMerge(node yamlmeta.Node, sourceStrategy schema.ArrayMergeStrategy) {
+	if mapItem, ok := node.(*yamlmeta.MapItem); ok {
+		if array, isArray := mapItem.Value.(*yamlmeta.Array); isArray && !hasOverlayAnnotations(mapItem) {
+			strategy := sourceStrategy
+			if o.loader.opts.ArrayMergeStrategy.IsSet() {
+				strategy = o.loader.opts.ArrayMergeStrategy
+			}
+			if itemType, ok := mapItem.Type.(*schema.MapItemType); ok && itemType.ArrayMerge().IsSet() {
+				strategy = itemType.ArrayMerge()
+			}
+			o.annotateArray(mapItem, array, strategy)
+		}
+	}
+
+	for _, val := range node.GetValues() {
+		if childNode, ok := val.(yamlmeta.Node); ok {
+			o.applyArrayMerge(childNode, sourceStrategy)
+		}
+	}
+}
+
+func (o DataValuesPreProcessing) annotateArray(mapItem *yamlmeta.MapItem, array *yamlmeta.Array, strategy schema.ArrayMergeStrategy) {
+	switch strategy.Op {
+	case schema.ArrayMergeReplace:
+		anns := template.NewAnnotations(mapItem)
+		anns[yttoverlay.AnnotationReplace] = template.NodeAnnotation{}
+		mapItem.SetAnnotations(anns)
+
+	case schema.ArrayMergeAppend:
+		for _, item := range array.Items {
+			if !hasOverlayAnnotations(item) {
+				anns := template.NewAnnotations(item)
+				anns[yttoverlay.AnnotationAppend] = template.NodeAnnotation{}
+				item.SetAnnotations(anns)
+			}
+		}
+
+	case schema.ArrayMergeByKey:
+		missingOK := starlark.Tuple{starlark.String(yttoverlay.MatchAnnotationKwargMissingOK), starlark.Bool(true)}
+
+		for _, item := range array.Items {
+			if !hasOverlayAnnotations(item) {
+				// matching item is merged (possibly with keys it did not have yet), otherwise item is appended
+				anns := template.NewAnnotations(item)
+				anns[yttoverlay.AnnotationMatch] = template.NodeAnnotation{Kwargs: []starlark.Tuple{
+					{starlark.String(yttoverlay.MatchAnnotationKwargBy), starlark.String(strategy.Key)}, missingOK}}
+				anns[yttoverlay.AnnotationMatchChildDefaults] = template.NodeAnnotation{Kwargs: []starlark.Tuple{missingOK}}
+				item.SetAnnotations(anns)
+			}
+		}
+	}
+}
+
+func hasOverlayAnnotations(node yamlmeta.Node) bool {
+	for name := range template.NewAnnotations(node) {
+		if strings.HasPrefix(string(name), string(yttoverlay.AnnotationNs)+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// collectDefaultArrays records arrays of map items (array items are not matched across data values)
+func (o DataValuesPreProcessing) collectDefaultArrays(val interface{}) {
+	switch typedVal := val.(type) {
+	case *yamlmeta.Map:
+		for _, item := range typedVal.Items {
+			o.collectDefaultArrays(item.Value)
+		}
+	case *yamlmeta.Array:
+		o.defaultArrays[typedVal] = true
+	}
+}
+
+// replaceDefaultArrays marks arrays given by newVal to replace (rather than be appended to)
+// arrays set by schema defaults, unless an overlay operation or array merge strategy was specified
+func (o DataValuesPreProcessing) replaceDefaultArrays(val, newVal interface{}) {
+	typedMap, ok := val.(*yamlmeta.Map)
+	if !ok {
+		return
+	}
+	newMap, ok := newVal.(*yamlmeta.Map)
+	if !ok {
+		return
+	}
+
+	items := map[interface{}]*yamlmeta.MapItem{}
+	for _, item := range typedMap.Items {
+		items[item.Key] = item
+	}
+
+	for _, newItem := range newMap.Items {
+		item, found := items[newItem.Key]
+		if !found {
+			continue
+		}
+
+		array, isArray := item.Value.(*yamlmeta.Array)
+		if !isArray || !o.defaultArrays[array] {
+			o.replaceDefaultArrays(item.Value, newItem.Value)
+			continue
+		}
+
+		// once given, arrays are combined with later values as usual
+		delete(o.defaultArrays, array)
+
+		if newArray, isArray := newItem.Value.(*yamlmeta.Array); isArray && !hasOverlayAnnotations(newItem) && !hasArrayItemOverlayAnnotations(newArray) {
+			anns := template.NewAnnotations(newItem)
+			anns[yttoverlay.AnnotationReplace] = template.NodeAnnotation{}
+			newItem.SetAnnotations(anns)
+		}
+	}
+}
+
+func hasArrayItemOverlayAnnotations(array *yamlmeta.Array) bool {
+	for _, item := range array.Items {
+		if hasOverlayAnnotations(item) {
+			return true
+		}
+	}
+	return false
+}
+
 func (o DataValuesPreProcessing) overlay(valuesDoc, newValuesDoc *yamlmeta.Document) (*yamlmeta.Document, error) {
+	o.replaceDefaultArrays(valuesDoc.Value, newValuesDoc.Value)
+
 	op := yttoverlay.Op{
 		Left:   &yamlmeta.DocumentSet{Items: []*yamlmeta.Document{valuesDoc}},
 		Right:  &yamlmeta.DocumentSet{Items: []*yamlmeta.Document{newValuesDoc}},
@@ -169,7 +499,23 @@ func (o DataValuesPreProcessing) overlayValuesOverlays(valuesDoc *yamlmeta.Docum
 	result = valuesDoc
 
 	for _, valuesOverlay := range o.valuesOverlays {
-		var err error
+		o.sensitive.Collect(valuesOverlay.Doc)
+
+		typeCheck, err := o.resetValues(valuesOverlay)
+		if err != nil {
+			return nil, err
+		}
+		if typeCheck.HasViolations() {
+			return nil, typeCheck
+		}
+
+		typeCheck = o.typeCheckOverlay(valuesOverlay.Doc, valuesOverlay.FromStrings)
+		if typeCheck.HasViolations() {
+			return nil, typeCheck
+		}
+
+		o.provenance.Collect(valuesOverlay.Doc)
+		o.applyArrayMerge(valuesOverlay.Doc, valuesOverlay.ArrayMerge)
 
 		result, err = o.overlay(result, valuesOverlay.Doc)
 		if err != nil {
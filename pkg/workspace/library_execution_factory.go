@@ -15,14 +15,34 @@ type LibraryExecutionContext struct {
 type LibraryExecutionFactory struct {
 	ui                 ui.UI
 	templateLoaderOpts TemplateLoaderOpts
+	valuesInspector    *LibraryValuesInspector
+	libRef             []LibRefPiece // of library being executed (empty for root library)
+	// sensitive values of all evaluated libraries (so that errors
+	// bubbling up to the root library could be masked as well)
+	librarySensitive *SensitiveValues
 }
 
 func NewLibraryExecutionFactory(ui ui.UI, templateLoaderOpts TemplateLoaderOpts) *LibraryExecutionFactory {
-	return &LibraryExecutionFactory{ui, templateLoaderOpts}
+	return &LibraryExecutionFactory{ui: ui, templateLoaderOpts: templateLoaderOpts, librarySensitive: NewSensitiveValues(nil)}
 }
 
 func (f *LibraryExecutionFactory) WithTemplateLoaderOptsOverrides(overrides TemplateLoaderOptsOverrides) *LibraryExecutionFactory {
-	return NewLibraryExecutionFactory(f.ui, f.templateLoaderOpts.Merge(overrides))
+	return &LibraryExecutionFactory{f.ui, f.templateLoaderOpts.Merge(overrides), f.valuesInspector, f.libRef, f.librarySensitive}
+}
+
+// WithValuesInspector returns a factory that reports final data values of evaluated libraries to inspector
+func (f *LibraryExecutionFactory) WithValuesInspector(inspector *LibraryValuesInspector) *LibraryExecutionFactory {
+	return &LibraryExecutionFactory{f.ui, f.templateLoaderOpts, inspector, f.libRef, f.librarySensitive}
+}
+
+func (f *LibraryExecutionFactory) forLibrary(refPiece LibRefPiece) *LibraryExecutionFactory {
+	libRef := append(append([]LibRefPiece{}, f.libRef...), refPiece)
+	return &LibraryExecutionFactory{f.ui, f.templateLoaderOpts, f.valuesInspector, libRef, f.librarySensitive}
+}
+
+// LibrarySensitiveValues returns sensitive values of libraries evaluated so far (and later on)
+func (f *LibraryExecutionFactory) LibrarySensitiveValues() *SensitiveValues {
+	return f.librarySensitive
 }
 
 func (f *LibraryExecutionFactory) New(ctx LibraryExecutionContext) *LibraryLoader {
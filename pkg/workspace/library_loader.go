@@ -56,6 +56,10 @@ func (ll *LibraryLoader) Schemas() ([]*yamlmeta.Document, error) {
 	}
 
 	if len(schemaFiles) > 0 {
+		if schemaFiles[0].File.Type() == files.TypeJSONSchema {
+			return ll.jsonSchemas(loader, schemaFiles[0])
+		}
+
 		libraryCtx := LibraryExecutionContext{Current: schemaFiles[0].Library, Root: NewRootLibrary(nil)}
 
 		_, resultDocSet, err := loader.EvalYAML(libraryCtx, schemaFiles[0].File)
@@ -75,6 +79,31 @@ func (ll *LibraryLoader) Schemas() ([]*yamlmeta.Document, error) {
 	return nil, nil
 }
 
+// Schema returns schema of this library's data values (depending on whether schema feature is enabled)
+func (ll *LibraryLoader) Schema() (Schema, error) {
+	schemaDocs, err := ll.Schemas()
+	if err != nil {
+		return nil, err
+	}
+
+	if !ll.templateLoaderOpts.SchemaEnabled {
+		if len(schemaDocs) > 0 {
+			ll.ui.Warnf("Warning: schema document was detected, but schema experiment flag is not enabled. Did you mean to include --enable-experiment-schema?\n")
+		}
+		return &schema.AnySchema{}, nil
+	}
+
+	if len(schemaDocs) == 0 {
+		return schema.NullSchema{}, nil
+	}
+
+	docSchema, err := schema.NewDocumentSchema(schemaDocs[0])
+	if err != nil {
+		return nil, err
+	}
+	return docSchema, nil
+}
+
 func (ll *LibraryLoader) Values(valuesOverlays []*DataValues, schema Schema) (*DataValues, []*DataValues, error) {
 	loader := NewTemplateLoader(NewEmptyDataValues(), nil, ll.ui, ll.templateLoaderOpts, ll.libraryExecFactory, schema)
 
@@ -83,10 +112,6 @@ func (ll *LibraryLoader) Values(valuesOverlays []*DataValues, schema Schema) (*D
 		return nil, nil, err
 	}
 
-	err = schema.ValidateWithValues(len(valuesFiles))
-	if err != nil {
-		return nil, nil, err
-	}
 	dvpp := DataValuesPreProcessing{
 		valuesFiles:           valuesFiles,
 		valuesOverlays:        valuesOverlays,
@@ -94,11 +119,52 @@ func (ll *LibraryLoader) Values(valuesOverlays []*DataValues, schema Schema) (*D
 		IgnoreUnknownComments: ll.templateLoaderOpts.IgnoreUnknownComments,
 	}
 
-	return dvpp.Apply()
+	values, libraryValues, err := dvpp.Apply()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = schema.Validate(values.Doc)
+	if err != nil {
+		return nil, nil, values.Sensitive.RedactError(fmt.Errorf("Validating data values: %s", err))
+	}
+
+	return values, libraryValues, nil
+}
+
+func (ll *LibraryLoader) jsonSchemas(loader *TemplateLoader, fileInLib *FileInLibrary) ([]*yamlmeta.Document, error) {
+	docSet, err := loader.ParseYAML(fileInLib.File)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, doc := range docSet.Items {
+		if doc.IsEmpty() {
+			continue
+		}
+		schemaDoc, err := schema.NewDocumentFromJSONSchema(doc)
+		if err != nil {
+			return nil, fmt.Errorf("Converting JSON Schema '%s': %s", fileInLib.File.RelativePath(), err)
+		}
+		return []*yamlmeta.Document{schemaDoc}, nil
+	}
+	return nil, nil
 }
 
 func (ll *LibraryLoader) schemaFiles(loader *TemplateLoader) ([]*FileInLibrary, error) {
-	return ll.filesByAnnotation(AnnotationSchemaMatch, loader)
+	schemaFiles, err := ll.filesByAnnotation(AnnotationSchemaMatch, loader)
+	if err != nil {
+		return nil, err
+	}
+
+	// files marked as JSON Schema (i.e. via file mark type=json-schema) are considered after schema files
+	for _, fileInLib := range ll.libraryCtx.Current.ListAccessibleFiles() {
+		if fileInLib.File.Type() == files.TypeJSONSchema {
+			schemaFiles = append(schemaFiles, fileInLib)
+		}
+	}
+
+	return schemaFiles, nil
 }
 
 func (ll *LibraryLoader) valuesFiles(loader *TemplateLoader) ([]*FileInLibrary, error) {
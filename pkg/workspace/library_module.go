@@ -72,7 +72,7 @@ func (b LibraryModule) Get(thread *starlark.Thread, f *starlark.Builtin,
 	libraryCtx := LibraryExecutionContext{Current: foundLib, Root: foundLib}
 
 	return (&libraryValue{libPath, libAlias, dataValuess, libraryCtx,
-		b.libraryExecutionFactory.WithTemplateLoaderOptsOverrides(tplLoaderOptsOverrides),
+		b.libraryExecutionFactory.WithTemplateLoaderOptsOverrides(tplLoaderOptsOverrides).forLibrary(LibRefPiece{Path: libPath, Alias: libAlias}),
 	}).AsStarlarkValue(), nil
 }
 
@@ -158,8 +158,11 @@ func (l *libraryValue) WithDataValues(thread *starlark.Thread, f *starlark.Built
 
 	dataValues := core.NewStarlarkValue(args.Index(0)).AsGoValue()
 
+	valuesAST := yamlmeta.NewASTFromInterface(dataValues)
+	l.setArgPositions(valuesAST, nil)
+
 	valsYAML, err := NewDataValues(&yamlmeta.Document{
-		Value:    yamlmeta.NewASTFromInterface(dataValues),
+		Value:    valuesAST,
 		Position: filepos.NewUnknownPosition(),
 	})
 	if err != nil {
@@ -175,6 +178,32 @@ func (l *libraryValue) WithDataValues(thread *starlark.Thread, f *starlark.Built
 	return libVal.AsStarlarkValue(), nil
 }
 
+// setArgPositions describes where values came from (e.g. for errors related to schema)
+func (l *libraryValue) setArgPositions(val interface{}, path []string) {
+	switch typedVal := val.(type) {
+	case *yamlmeta.Map:
+		for _, item := range typedVal.Items {
+			itemPath := append(append([]string{}, path...), fmt.Sprintf("%v", item.Key))
+
+			pos := filepos.NewPosition(1)
+			pos.SetFile(fmt.Sprintf("key '%s' (library.with_data_values arg)", strings.Join(itemPath, ".")))
+			item.Position = pos
+
+			l.setArgPositions(item.Value, itemPath)
+		}
+	case *yamlmeta.Array:
+		for i, item := range typedVal.Items {
+			itemPath := append(append([]string{}, path...), fmt.Sprintf("%d", i))
+
+			pos := filepos.NewPosition(1)
+			pos.SetFile(fmt.Sprintf("key '%s' (library.with_data_values arg)", strings.Join(itemPath, ".")))
+			item.Position = pos
+
+			l.setArgPositions(item.Value, itemPath)
+		}
+	}
+}
+
 func (l *libraryValue) Eval(thread *starlark.Thread, f *starlark.Builtin,
 	args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
 
@@ -318,11 +347,23 @@ func (l *libraryValue) libraryValues(ll *LibraryLoader) (*DataValues, []*DataVal
 		}
 	}
 
-	dvs, foundChildDVss, err := ll.Values(append(dvss, afterLibModDVss...), &schema.AnySchema{})
+	libSchema, err := ll.Schema()
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, isNull := libSchema.(schema.NullSchema); isNull {
+		// unlike root library, libraries are not required to have schema
+		libSchema = &schema.AnySchema{}
+	}
+
+	dvs, foundChildDVss, err := ll.Values(append(dvss, afterLibModDVss...), libSchema)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	l.libraryExecutionFactory.valuesInspector.observe(l.libraryExecutionFactory.libRef, dvs)
+	l.libraryExecutionFactory.librarySensitive.Include(dvs.Sensitive)
+
 	// Order data values specified in a parent library, on top of
 	// data values specified within a child library
 	foundChildDVss = append(foundChildDVss, childDVss...)
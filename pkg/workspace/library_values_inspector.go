@@ -0,0 +1,35 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package workspace
+
+// LibraryValuesInspector collects final data values of libraries matching
+// a library ref (e.g. @lib1~alias@nested) as those libraries are evaluated.
+// Library is evaluated (and hence collected) once per library.get(...) call.
+type LibraryValuesInspector struct {
+	libRef []LibRefPiece
+	values []*DataValues
+}
+
+func NewLibraryValuesInspector(libRefStr string) (*LibraryValuesInspector, error) {
+	libRef, err := parseLibRefStr(libRefStr)
+	if err != nil {
+		return nil, err
+	}
+	return &LibraryValuesInspector{libRef: libRef}, nil
+}
+
+// Values returns data values of matching libraries in order of their evaluation
+func (i *LibraryValuesInspector) Values() []*DataValues { return i.values }
+
+func (i *LibraryValuesInspector) observe(libRef []LibRefPiece, values *DataValues) {
+	if i == nil || len(libRef) != len(i.libRef) {
+		return
+	}
+	for idx, refPiece := range i.libRef {
+		if !refPiece.Matches(libRef[idx]) {
+			return
+		}
+	}
+	i.values = append(i.values, values)
+}
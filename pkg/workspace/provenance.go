@@ -0,0 +1,185 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package workspace
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/k14s/ytt/pkg/filepos"
+	"github.com/k14s/ytt/pkg/yamlmeta"
+)
+
+// Provenance keeps track of sources (schema defaults, data values files, command line flags)
+// that set each data value, in order they were applied. Array items are not matched across sources,
+// hence each array item (and values within it) is attributed only to the source it came from.
+type Provenance struct {
+	sources map[string][]string
+	// defaults holds positions of array items that came from schema defaults
+	defaults map[string]bool
+}
+
+func NewProvenance() *Provenance {
+	return &Provenance{sources: map[string][]string{}, defaults: map[string]bool{}}
+}
+
+// CollectDefaults records values of doc as set by schema defaults
+func (p *Provenance) CollectDefaults(doc *yamlmeta.Document) {
+	if p == nil || doc == nil {
+		return
+	}
+	p.collect(doc.Value, nil, defaultSuffix)
+	p.collectDefaultArrayItems(doc.Value)
+}
+
+// Collect records values of doc as set by the source doc came from
+func (p *Provenance) Collect(doc *yamlmeta.Document) {
+	if p == nil || doc == nil {
+		return
+	}
+	p.collect(doc.Value, nil, "")
+}
+
+const defaultSuffix = " (schema default)"
+
+func (p *Provenance) collect(val interface{}, path []string, suffix string) {
+	typedMap, ok := val.(*yamlmeta.Map)
+	if !ok {
+		return
+	}
+	for _, item := range typedMap.Items {
+		itemPath := append(append([]string{}, path...), fmt.Sprintf("%v", item.Key))
+
+		if childMap, isMap := item.Value.(*yamlmeta.Map); isMap && len(childMap.Items) > 0 {
+			p.collect(childMap, itemPath, suffix)
+			continue
+		}
+
+		key := p.key(itemPath)
+		p.sources[key] = append(p.sources[key], item.Position.AsCompactString()+suffix)
+	}
+}
+
+func (p *Provenance) collectDefaultArrayItems(val interface{}) {
+	switch typedVal := val.(type) {
+	case *yamlmeta.Map:
+		for _, item := range typedVal.Items {
+			p.collectDefaultArrayItems(item.Value)
+		}
+	case *yamlmeta.Array:
+		for _, item := range typedVal.Items {
+			if item.Position.IsKnown() {
+				p.defaults[item.Position.AsCompactString()] = true
+			}
+			p.collectDefaultArrayItems(item.Value)
+		}
+	}
+}
+
+// AnnotateDocument returns a copy of doc with a comment next to each value listing sources that set it
+func (p *Provenance) AnnotateDocument(doc *yamlmeta.Document) *yamlmeta.Document {
+	if p == nil || doc == nil {
+		return doc
+	}
+	result := doc.DeepCopy()
+	p.clearComments(result)
+	lineNum := 0
+	p.annotate(result.Value, nil, false, &lineNum)
+	return result
+}
+
+func (p *Provenance) annotate(val interface{}, path []string, inArray bool, lineNum *int) {
+	switch typedVal := val.(type) {
+	case *yamlmeta.Map:
+		for _, item := range typedVal.Items {
+			itemPath := append(append([]string{}, path...), fmt.Sprintf("%v", item.Key))
+
+			// comments are printed on the same line as their item only if both positions match
+			*lineNum++
+			sources := p.sources[p.key(itemPath)]
+			if inArray {
+				sources = p.ownSources(item.Position)
+			}
+			item.Position = filepos.NewPosition(*lineNum)
+
+			if p.isLeaf(item.Value) {
+				item.Metas = p.comment(sources, item.Position)
+				continue
+			}
+			p.annotate(item.Value, itemPath, inArray, lineNum)
+		}
+
+	case *yamlmeta.Array:
+		for _, item := range typedVal.Items {
+			*lineNum++
+			sources := p.ownSources(item.Position)
+			item.Position = filepos.NewPosition(*lineNum)
+
+			if p.isLeaf(item.Value) {
+				item.Metas = p.comment(sources, item.Position)
+				continue
+			}
+			p.annotate(item.Value, path, true, lineNum)
+		}
+	}
+}
+
+// isLeaf returns true for values printed on the same line as their item (including empty collections)
+func (Provenance) isLeaf(val interface{}) bool {
+	switch typedVal := val.(type) {
+	case *yamlmeta.Map:
+		return len(typedVal.Items) == 0
+	case *yamlmeta.Array:
+		return len(typedVal.Items) == 0
+	default:
+		return true
+	}
+}
+
+func (p *Provenance) ownSources(pos *filepos.Position) []string {
+	if !pos.IsKnown() {
+		return nil
+	}
+	source := pos.AsCompactString()
+	if p.defaults[source] {
+		source += defaultSuffix
+	}
+	return []string{source}
+}
+
+func (Provenance) comment(sources []string, pos *filepos.Position) []*yamlmeta.Meta {
+	if len(sources) == 0 {
+		return nil
+	}
+	return []*yamlmeta.Meta{{Data: " " + strings.Join(sources, ", "), Position: pos}}
+}
+
+// clearComments removes comments (and annotations) carried over from data values files
+func (p *Provenance) clearComments(node yamlmeta.Node) {
+	switch typedNode := node.(type) {
+	case *yamlmeta.Document:
+		typedNode.Metas = nil
+	case *yamlmeta.Map:
+		typedNode.Metas = nil
+	case *yamlmeta.MapItem:
+		typedNode.Metas = nil
+	case *yamlmeta.Array:
+		typedNode.Metas = nil
+	case *yamlmeta.ArrayItem:
+		typedNode.Metas = nil
+	}
+	for _, val := range node.GetValues() {
+		if childNode, ok := val.(yamlmeta.Node); ok {
+			p.clearComments(childNode)
+		}
+	}
+}
+
+func (Provenance) key(path []string) string {
+	var quoted []string
+	for _, piece := range path {
+		quoted = append(quoted, fmt.Sprintf("%q", piece))
+	}
+	return strings.Join(quoted, ".")
+}
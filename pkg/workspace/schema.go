@@ -11,6 +11,7 @@ type Schema interface {
 	AssignType(typeable yamlmeta.Typeable) yamlmeta.TypeCheck
 	AsDataValue() *yamlmeta.Document
 	ValidateWithValues(valuesFilesCount int) error
+	Validate(values *yamlmeta.Document) error
 }
 
 var _ Schema = &schema.AnySchema{}
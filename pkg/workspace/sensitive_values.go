@@ -0,0 +1,232 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/k14s/ytt/pkg/schema"
+	"github.com/k14s/ytt/pkg/structmeta"
+	"github.com/k14s/ytt/pkg/template"
+	"github.com/k14s/ytt/pkg/yamlmeta"
+)
+
+const (
+	AnnotationDataSensitive structmeta.AnnotationName = "data/sensitive"
+
+	// shorter values (as well as booleans) are not masked within arbitrary text
+	// (e.g. error messages) since they are likely to occur in unrelated text
+	minSubstringRedactableLen = 4
+)
+
+// SensitiveValues keeps track of data values marked as sensitive
+// (via @schema/sensitive or @data/sensitive) so that they could be masked
+// wherever data values may be shown (other than in templates' output).
+//
+// Data values (e.g. --data-values-inspect) are masked by their location.
+// Elsewhere (e.g. messages of assert.fail(...), debug output) occurrences of
+// sensitive values themselves are masked. Values derived from sensitive
+// values (e.g. encoded or concatenated) are not known, hence not masked.
+type SensitiveValues struct {
+	paths  []sensitivePath
+	values map[string]struct{}
+	// sensitive values of libraries evaluated on behalf of this library
+	included []*SensitiveValues
+}
+
+// sensitivePath is a sequence of map keys (and arrayItemsStep for items of arrays)
+type sensitivePath []interface{}
+
+type arrayItemsStep struct{}
+
+func NewSensitiveValues(currSchema Schema) *SensitiveValues {
+	s := &SensitiveValues{values: map[string]struct{}{}}
+	if docSchema, ok := currSchema.(*schema.DocumentSchema); ok {
+		s.addSchemaPaths(docSchema.Allowed.ValueType, nil)
+	}
+	return s
+}
+
+func (s *SensitiveValues) addSchemaPaths(valueType yamlmeta.Type, path sensitivePath) {
+	switch typedType := valueType.(type) {
+	case *schema.MapType:
+		for _, item := range typedType.Items {
+			itemPath := path.append(item.Key)
+			if item.IsSensitive() {
+				s.paths = append(s.paths, itemPath)
+				continue
+			}
+			s.addSchemaPaths(item.ValueType, itemPath)
+		}
+	case *schema.ArrayType:
+		s.addSchemaPaths(typedType.ItemsType.GetValueType(), path.append(arrayItemsStep{}))
+	}
+}
+
+// Collect records items of doc annotated with @data/sensitive
+// and remembers values of all sensitive items found in doc.
+func (s *SensitiveValues) Collect(doc *yamlmeta.Document) {
+	if s == nil || doc == nil {
+		return
+	}
+	s.addAnnotatedPaths(doc, nil)
+	s.collectValues(doc, nil, false)
+}
+
+func (s *SensitiveValues) addAnnotatedPaths(node yamlmeta.Node, path sensitivePath) {
+	switch typedNode := node.(type) {
+	case *yamlmeta.MapItem:
+		path = path.append(typedNode.Key)
+		if template.NewAnnotations(typedNode).Has(AnnotationDataSensitive) && !s.isSensitive(path) {
+			s.paths = append(s.paths, path)
+		}
+	case *yamlmeta.ArrayItem:
+		path = path.append(arrayItemsStep{})
+	}
+
+	for _, val := range node.GetValues() {
+		if childNode, ok := val.(yamlmeta.Node); ok {
+			s.addAnnotatedPaths(childNode, path)
+		}
+	}
+}
+
+func (s *SensitiveValues) collectValues(node yamlmeta.Node, path sensitivePath, sensitive bool) {
+	switch typedNode := node.(type) {
+	case *yamlmeta.MapItem:
+		path = path.append(typedNode.Key)
+	case *yamlmeta.ArrayItem:
+		path = path.append(arrayItemsStep{})
+	}
+	sensitive = sensitive || s.isSensitive(path)
+
+	for _, val := range node.GetValues() {
+		switch typedVal := val.(type) {
+		case yamlmeta.Node:
+			s.collectValues(typedVal, path, sensitive)
+		case nil, bool:
+			// absence of a value does not need masking; booleans are too common to mask
+		default:
+			if str := fmt.Sprintf("%v", typedVal); sensitive && len(str) >= minSubstringRedactableLen {
+				s.values[str] = struct{}{}
+			}
+		}
+	}
+}
+
+func (s *SensitiveValues) isSensitive(path sensitivePath) bool {
+	for _, sensitivePath := range s.paths {
+		if sensitivePath.equals(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactDocument returns a copy of doc with values of all sensitive items masked
+func (s *SensitiveValues) RedactDocument(doc *yamlmeta.Document) *yamlmeta.Document {
+	if s == nil || len(s.paths) == 0 {
+		return doc
+	}
+	result := doc.DeepCopy()
+	s.redactNode(result, nil)
+	return result
+}
+
+func (s *SensitiveValues) redactNode(node yamlmeta.Node, path sensitivePath) {
+	switch typedNode := node.(type) {
+	case *yamlmeta.MapItem:
+		path = path.append(typedNode.Key)
+		if s.isSensitive(path) {
+			typedNode.Value = redactedValueOf(typedNode.Value)
+			return
+		}
+	case *yamlmeta.ArrayItem:
+		path = path.append(arrayItemsStep{})
+	}
+
+	for _, val := range node.GetValues() {
+		if childNode, ok := val.(yamlmeta.Node); ok {
+			s.redactNode(childNode, path)
+		}
+	}
+}
+
+// redactedValueOf keeps absence of a value visible (i.e. nulls are not masked)
+func redactedValueOf(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	return schema.RedactedValue
+}
+
+// Include makes values of other (which may still be collecting values) masked by s as well
+func (s *SensitiveValues) Include(other *SensitiveValues) {
+	if s == nil || other == nil || s == other {
+		return
+	}
+	s.included = append(s.included, other)
+}
+
+func (s *SensitiveValues) allValues(result map[string]struct{}) {
+	for val := range s.values {
+		result[val] = struct{}{}
+	}
+	for _, other := range s.included {
+		other.allValues(result)
+	}
+}
+
+// Redact masks occurrences of sensitive values in str
+// (other than booleans and values shorter than minSubstringRedactableLen)
+func (s *SensitiveValues) Redact(str string) string {
+	if s == nil {
+		return str
+	}
+	allValues := map[string]struct{}{}
+	s.allValues(allValues)
+
+	var values []string
+	for val := range allValues {
+		values = append(values, val)
+	}
+	// replace longer values first in case some values include others
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+
+	for _, val := range values {
+		str = strings.ReplaceAll(str, val, schema.RedactedValue)
+	}
+	return str
+}
+
+func (s *SensitiveValues) RedactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	errMsg := s.Redact(err.Error())
+	if errMsg == err.Error() {
+		return err
+	}
+	return errors.New(errMsg)
+}
+
+func (p sensitivePath) append(step interface{}) sensitivePath {
+	result := append(sensitivePath{}, p...)
+	return append(result, step)
+}
+
+func (p sensitivePath) equals(other sensitivePath) bool {
+	if len(p) != len(other) {
+		return false
+	}
+	for i := range p {
+		if p[i] != other[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -6,10 +6,12 @@ package workspace
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/k14s/starlark-go/starlark"
 	"github.com/k14s/ytt/pkg/cmd/ui"
 	"github.com/k14s/ytt/pkg/files"
+	"github.com/k14s/ytt/pkg/schema"
 	"github.com/k14s/ytt/pkg/template"
 	"github.com/k14s/ytt/pkg/texttemplate"
 	"github.com/k14s/ytt/pkg/yamlmeta"
@@ -31,6 +33,11 @@ type TemplateLoaderOpts struct {
 	IgnoreUnknownComments   bool
 	ImplicitMapKeyOverrides bool
 	StrictYAML              bool
+	SchemaEnabled           bool
+	// applies to arrays of all data values overlays unless schema specifies otherwise
+	ArrayMergeStrategy schema.ArrayMergeStrategy
+	// allows templates to access current time (output is no longer reproducible)
+	AllowClock bool
 }
 
 type TemplateLoaderOptsOverrides struct {
@@ -179,7 +186,8 @@ func (l *TemplateLoader) EvalYAML(libraryCtx LibraryExecutionContext, file *file
 
 	yttLibrary := yttlibrary.NewAPI(compiledTemplate.TplReplaceNode,
 		yttlibrary.NewDataModule(l.values.Doc, DataLoader{libraryCtx}),
-		NewLibraryModule(libraryCtx, l.libraryExecFactory, l.libraryValuess).AsModule())
+		NewLibraryModule(libraryCtx, l.libraryExecFactory, l.libraryValuess).AsModule(),
+		l.timeModule())
 
 	thread := l.newThread(libraryCtx, yttLibrary, file)
 
@@ -221,7 +229,8 @@ func (l *TemplateLoader) EvalText(libraryCtx LibraryExecutionContext, file *file
 
 	yttLibrary := yttlibrary.NewAPI(compiledTemplate.TplReplaceNode,
 		yttlibrary.NewDataModule(l.values.Doc, DataLoader{libraryCtx}),
-		NewLibraryModule(libraryCtx, l.libraryExecFactory, l.libraryValuess).AsModule())
+		NewLibraryModule(libraryCtx, l.libraryExecFactory, l.libraryValuess).AsModule(),
+		l.timeModule())
 
 	thread := l.newThread(libraryCtx, yttLibrary, file)
 
@@ -251,7 +260,8 @@ func (l *TemplateLoader) EvalStarlark(libraryCtx LibraryExecutionContext, file *
 
 	yttLibrary := yttlibrary.NewAPI(compiledTemplate.TplReplaceNode,
 		yttlibrary.NewDataModule(l.values.Doc, DataLoader{libraryCtx}),
-		NewLibraryModule(libraryCtx, l.libraryExecFactory, l.libraryValuess).AsModule())
+		NewLibraryModule(libraryCtx, l.libraryExecFactory, l.libraryValuess).AsModule(),
+		l.timeModule())
 
 	thread := l.newThread(libraryCtx, yttLibrary, file)
 
@@ -315,6 +325,14 @@ func (l *TemplateLoader) newThread(libraryCtx LibraryExecutionContext,
 	return thread
 }
 
+// timeModule only gives access to current time when explicitly allowed
+func (l *TemplateLoader) timeModule() yttlibrary.TimeModule {
+	if l.opts.AllowClock {
+		return yttlibrary.NewTimeModule(time.Now)
+	}
+	return yttlibrary.NewTimeModule(nil)
+}
+
 func (l *TemplateLoader) addCompiledTemplate(path string, ct *template.CompiledTemplate) {
 	l.compiledTemplates[path] = ct
 }
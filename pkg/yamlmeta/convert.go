@@ -5,6 +5,7 @@ package yamlmeta
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/k14s/ytt/pkg/filepos"
 	"github.com/k14s/ytt/pkg/orderedmap"
@@ -49,6 +50,52 @@ func convertToLowYAML(val interface{}) interface{} {
 	}
 }
 
+func convertToLowYAMLWithComments(val interface{}) interface{} {
+	switch typedVal := val.(type) {
+	case *Map:
+		result := yaml.MapSlice{}
+		for _, item := range typedVal.Items {
+			headComments, lineComment := splitComments(item)
+			result = append(result, yaml.MapItem{
+				Key:   yaml.CommentedValue{Value: item.Key, HeadComments: headComments},
+				Value: yaml.CommentedValue{Value: convertToLowYAMLWithComments(item.Value), LineComment: lineComment},
+			})
+		}
+		return result
+
+	case *Array:
+		result := []interface{}{}
+		for _, item := range typedVal.Items {
+			headComments, lineComment := splitComments(item)
+			result = append(result, yaml.CommentedValue{
+				Value:        convertToLowYAMLWithComments(item.Value),
+				HeadComments: headComments,
+				LineComment:  lineComment,
+			})
+		}
+		return result
+
+	default:
+		return convertToLowYAML(convertToGo(val))
+	}
+}
+
+// splitComments returns comments on lines before node and the last comment on the same line as node
+func splitComments(node Node) ([]string, string) {
+	var headComments []string
+	var lineComment string
+
+	for _, meta := range node.GetMetas() {
+		data := strings.Replace(meta.Data, "\n", " ", -1)
+		if node.GetPosition().IsKnown() && meta.Position.IsKnown() && meta.Position.LineNum() == node.GetPosition().LineNum() {
+			lineComment = data
+		} else {
+			headComments = append(headComments, data)
+		}
+	}
+	return headComments, lineComment
+}
+
 func convertToGo(val interface{}) interface{} {
 	switch typedVal := val.(type) {
 	case *DocumentSet:
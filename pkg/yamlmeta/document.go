@@ -4,6 +4,8 @@
 package yamlmeta
 
 import (
+	"strings"
+
 	"github.com/k14s/ytt/pkg/yamlmeta/internal/yaml.v2"
 )
 
@@ -25,6 +27,21 @@ func (d *Document) AsYAMLBytes() ([]byte, error) {
 	return yaml.Marshal(convertToLowYAML(convertToGo(d.Value)))
 }
 
+// AsYAMLBytesWithComments is like AsYAMLBytes, but also includes comments of the document (followed by
+// document start), map items and array items. Comments on the same line as their item remain on the same line.
+func (d *Document) AsYAMLBytesWithComments() ([]byte, error) {
+	bs, err := yaml.Marshal(convertToLowYAMLWithComments(d.Value))
+	if err != nil || len(d.Metas) == 0 {
+		return bs, err
+	}
+
+	var header []byte
+	for _, meta := range d.Metas {
+		header = append(header, "#"+strings.Replace(meta.Data, "\n", " ", -1)+"\n"...)
+	}
+	return append(append(header, "---\n"...), bs...), nil
+}
+
 func (d *Document) AsInterface() interface{} {
 	return convertToGo(d.Value)
 }
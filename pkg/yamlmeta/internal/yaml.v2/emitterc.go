@@ -483,6 +483,9 @@ func yamlEmitterEmitFlowSequenceItem(emitter *yamlEmitterT, event *yamlEventT, f
 		if !yamlEmitterWriteIndicator(emitter, []byte{']'}, false, false, false) {
 			return false
 		}
+		if emitter.flowLevel == 0 && !yamlEmitterWriteLineComment(emitter) {
+			return false
+		}
 		emitter.state = emitter.states[len(emitter.states)-1]
 		emitter.states = emitter.states[:len(emitter.states)-1]
 
@@ -531,6 +534,9 @@ func yamlEmitterEmitFlowMappingKey(emitter *yamlEmitterT, event *yamlEventT, fir
 		if !yamlEmitterWriteIndicator(emitter, []byte{'}'}, false, false, false) {
 			return false
 		}
+		if emitter.flowLevel == 0 && !yamlEmitterWriteLineComment(emitter) {
+			return false
+		}
 		emitter.state = emitter.states[len(emitter.states)-1]
 		emitter.states = emitter.states[:len(emitter.states)-1]
 		return true
@@ -592,6 +598,9 @@ func yamlEmitterEmitBlockSequenceItem(emitter *yamlEmitterT, event *yamlEventT,
 		emitter.states = emitter.states[:len(emitter.states)-1]
 		return true
 	}
+	if !yamlEmitterWriteHeadComments(emitter, event) {
+		return false
+	}
 	if !yamlEmitterWriteIndent(emitter) {
 		return false
 	}
@@ -616,6 +625,9 @@ func yamlEmitterEmitBlockMappingKey(emitter *yamlEmitterT, event *yamlEventT, fi
 		emitter.states = emitter.states[:len(emitter.states)-1]
 		return true
 	}
+	if !yamlEmitterWriteHeadComments(emitter, event) {
+		return false
+	}
 	if !yamlEmitterWriteIndent(emitter) {
 		return false
 	}
@@ -696,9 +708,13 @@ func yamlEmitterEmitScalar(emitter *yamlEmitterT, event *yamlEventT) bool {
 	if !yamlEmitterIncreaseIndent(emitter, true, false) {
 		return false
 	}
+	emitter.lineComment = event.lineComment
 	if !yamlEmitterProcessScalar(emitter) {
 		return false
 	}
+	if !yamlEmitterWriteLineComment(emitter) {
+		return false
+	}
 	emitter.indent = emitter.indents[len(emitter.indents)-1]
 	emitter.indents = emitter.indents[:len(emitter.indents)-1]
 	emitter.state = emitter.states[len(emitter.states)-1]
@@ -716,6 +732,9 @@ func yamlEmitterEmitSequenceStart(emitter *yamlEmitterT, event *yamlEventT) bool
 	}
 	if emitter.flowLevel > 0 || emitter.canonical || event.sequenceStyle() == yamlFlowSequenceStyle ||
 		yamlEmitterCheckEmptySequence(emitter) {
+		if emitter.flowLevel == 0 {
+			emitter.lineComment = event.lineComment
+		}
 		emitter.state = yamlEmitFlowSequenceFirstItemState
 	} else {
 		emitter.state = yamlEmitBlockSequenceFirstItemState
@@ -733,6 +752,9 @@ func yamlEmitterEmitMappingStart(emitter *yamlEmitterT, event *yamlEventT) bool
 	}
 	if emitter.flowLevel > 0 || emitter.canonical || event.mappingStyle() == yamlFlowMappingStyle ||
 		yamlEmitterCheckEmptyMapping(emitter) {
+		if emitter.flowLevel == 0 {
+			emitter.lineComment = event.lineComment
+		}
 		emitter.state = yamlEmitFlowMappingFirstKeyState
 	} else {
 		emitter.state = yamlEmitBlockMappingFirstKeyState
@@ -1550,6 +1572,41 @@ func yamlEmitterWriteDoubleQuotedScalar(emitter *yamlEmitterT, value []byte, all
 	return true
 }
 
+// Write comments of the node (if any) each on its own line.
+func yamlEmitterWriteHeadComments(emitter *yamlEmitterT, event *yamlEventT) bool {
+	for _, comment := range event.headComments {
+		if !yamlEmitterWriteIndent(emitter) {
+			return false
+		}
+		if !yamlEmitterWriteIndicator(emitter, []byte{'#'}, false, false, false) {
+			return false
+		}
+		if !writeAll(emitter, comment) {
+			return false
+		}
+		emitter.whitespace = false
+		emitter.indention = false
+	}
+	return true
+}
+
+// Write a pending line comment (if any) at the end of the current line.
+func yamlEmitterWriteLineComment(emitter *yamlEmitterT) bool {
+	if len(emitter.lineComment) == 0 {
+		return true
+	}
+	if !yamlEmitterWriteIndicator(emitter, []byte{'#'}, true, false, false) {
+		return false
+	}
+	if !writeAll(emitter, emitter.lineComment) {
+		return false
+	}
+	emitter.lineComment = nil
+	emitter.whitespace = false
+	emitter.indention = false
+	return true
+}
+
 func yamlEmitterWriteBlockScalarHints(emitter *yamlEmitterT, value []byte) bool {
 	if isSpace(value, 0) || isBreak(value, 0) {
 		indentHint := []byte{'0' + byte(emitter.bestIndent)}
@@ -1599,6 +1656,9 @@ func yamlEmitterWriteLiteralScalar(emitter *yamlEmitterT, value []byte) bool {
 	if !yamlEmitterWriteBlockScalarHints(emitter, value) {
 		return false
 	}
+	if !yamlEmitterWriteLineComment(emitter) {
+		return false
+	}
 	if !putBreak(emitter) {
 		return false
 	}
@@ -1636,6 +1696,9 @@ func yamlEmitterWriteFoldedScalar(emitter *yamlEmitterT, value []byte) bool {
 	if !yamlEmitterWriteBlockScalarHints(emitter, value) {
 		return false
 	}
+	if !yamlEmitterWriteLineComment(emitter) {
+		return false
+	}
 
 	if !putBreak(emitter) {
 		return false
@@ -37,6 +37,9 @@ type encoder struct {
 	// doneInit holds whether the initial stream_start_event has been
 	// emitted.
 	doneInit bool
+	// headComments and lineComment are attached to the next emitted node.
+	headComments [][]byte
+	lineComment  []byte
 }
 
 func newEncoder() *encoder {
@@ -75,6 +78,11 @@ func (e *encoder) destroy() {
 }
 
 func (e *encoder) emit() {
+	switch e.event.typ {
+	case yamlScalarEvent, yamlSequenceStartEvent, yamlMappingStartEvent:
+		e.event.headComments, e.headComments = e.headComments, nil
+		e.event.lineComment, e.lineComment = e.lineComment, nil
+	}
 	// This will internally delete the e.event value.
 	e.must(yamlEmitterEmit(&e.emitter, &e.event))
 }
@@ -105,6 +113,15 @@ func (e *encoder) marshal(tag string, in reflect.Value) {
 	}
 	iface := in.Interface()
 	switch m := iface.(type) {
+	case CommentedValue:
+		for _, comment := range m.HeadComments {
+			e.headComments = append(e.headComments, []byte(comment))
+		}
+		if len(m.LineComment) > 0 {
+			e.lineComment = []byte(m.LineComment)
+		}
+		e.marshal(tag, reflect.ValueOf(m.Value))
+		return
 	case jsonNumber:
 		integer, err := m.Int64()
 		if err == nil {
@@ -342,6 +342,27 @@ var marshalTests = []struct {
 		"b: 2\na: 1\nd: 4\nc: 3\nsub:\n  e: 5\n",
 	},
 
+	// Line comments.
+	{
+		yaml.MapSlice{
+			{"a", yaml.CommentedValue{Value: 1, LineComment: " one"}, 0},
+			{"b", yaml.CommentedValue{Value: []interface{}{}, LineComment: " empty"}, 0},
+			{"c", yaml.CommentedValue{Value: yaml.MapSlice{}, LineComment: " empty"}, 0},
+			{"d", []interface{}{yaml.CommentedValue{Value: "x", LineComment: " item"}}, 0},
+			{"e", yaml.CommentedValue{Value: "multi\nline", LineComment: " text"}, 0},
+			{"f", yaml.CommentedValue{Value: yaml.MapSlice{{"g", nil, 0}}, LineComment: " ignored"}, 0},
+		},
+		"a: 1 # one\nb: [] # empty\nc: {} # empty\nd:\n- x # item\ne: |- # text\n  multi\n  line\nf:\n  g: null\n",
+	}, {
+		yaml.MapSlice{
+			{yaml.CommentedValue{Value: "a", HeadComments: []string{"first", " second"}}, 1, 0},
+			{"b", yaml.MapSlice{{yaml.CommentedValue{Value: true, HeadComments: []string{"key"}}, []interface{}{
+				yaml.CommentedValue{Value: yaml.MapSlice{{"c", 1, 0}}, HeadComments: []string{"item"}},
+			}, 0}}, 0},
+		},
+		"#first\n# second\na: 1\nb:\n  #key\n  true:\n  #item\n  - c: 1\n",
+	},
+
 	// Encode unicode as utf-8 rather than in escaped form.
 	{
 		map[string]string{"a": "你好"},
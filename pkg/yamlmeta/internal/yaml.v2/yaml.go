@@ -33,6 +33,15 @@ type ArrayItem struct {
 	Line  int
 }
 
+// CommentedValue encodes as its Value preceded by head comments (each on its own line;
+// only for block mapping keys and block sequence items) and followed by a line comment
+// at the end of the line Value ends on (ignored for block collections).
+type CommentedValue struct {
+	Value        interface{}
+	HeadComments []string
+	LineComment  string
+}
+
 // The Unmarshaler interface may be implemented by types to customize their
 // behavior when being unmarshaled from a YAML document. The UnmarshalYAML
 // method receives a function that may be called to unmarshal the original
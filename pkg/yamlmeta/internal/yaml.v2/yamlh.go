@@ -307,6 +307,12 @@ type yamlEventT struct {
 
 	// The style (for yaml_SCALAR_EVENT, yaml_SEQUENCE_START_EVENT, yaml_MAPPING_START_EVENT).
 	style yamlStyleT
+
+	// The comments on lines before the node (for yaml_SCALAR_EVENT, yaml_SEQUENCE_START_EVENT, yaml_MAPPING_START_EVENT).
+	headComments [][]byte
+
+	// The comment at the end of the line node ends on (for yaml_SCALAR_EVENT, yaml_SEQUENCE_START_EVENT, yaml_MAPPING_START_EVENT).
+	lineComment []byte
 }
 
 func (e *yamlEventT) scalarStyle() yamlScalarStyleT     { return yamlScalarStyleT(e.style) }
@@ -710,6 +716,8 @@ type yamlEmitterT struct {
 	indention  bool // If the last character was an indentation character (' ', '-', '?', ':')?
 	openEnded  bool // If an explicit document end is required?
 
+	lineComment []byte // The comment to write at the end of the current node.
+
 	// Anchor analysis.
 	anchorData struct {
 		anchor []byte // The anchor value.
@@ -30,7 +30,7 @@ func (s *Scalar) ValueTypeAsString() string       { return typeToString(s.Value)
 
 func typeToString(value interface{}) string {
 	switch value.(type) {
-	case int:
+	case int, int64, uint64:
 		return "integer"
 	case bool:
 		return "boolean"
@@ -16,29 +16,45 @@ type DocumentPrinter interface {
 }
 
 type YAMLPrinter struct {
-	buf         io.Writer
-	writtenOnce bool
+	buf          io.Writer
+	writtenOnce  bool
+	withComments bool
 }
 
 var _ DocumentPrinter = &YAMLPrinter{}
 
 func NewYAMLPrinter(writer io.Writer) *YAMLPrinter {
-	return &YAMLPrinter{writer, false}
+	return &YAMLPrinter{buf: writer}
+}
+
+// NewYAMLPrinterWithComments prints comments that are on the same line as their map or array item
+func NewYAMLPrinterWithComments(writer io.Writer) *YAMLPrinter {
+	return &YAMLPrinter{buf: writer, withComments: true}
 }
 
 func (p *YAMLPrinter) Print(item *Document) error {
 	if p.writtenOnce {
-		p.buf.Write([]byte("---\n")) // TODO use encoder?
+		_, err := p.buf.Write([]byte("---\n")) // TODO use encoder?
+		if err != nil {
+			return err
+		}
 	} else {
 		p.writtenOnce = true
 	}
 
-	bs, err := item.AsYAMLBytes()
+	var bs []byte
+	var err error
+
+	if p.withComments {
+		bs, err = item.AsYAMLBytesWithComments()
+	} else {
+		bs, err = item.AsYAMLBytes()
+	}
 	if err != nil {
 		return fmt.Errorf("marshaling doc: %s", err)
 	}
-	p.buf.Write(bs)
-	return nil
+	_, err = p.buf.Write(bs)
+	return err
 }
 
 type JSONPrinter struct {
@@ -188,7 +188,7 @@ func (l stdTemplateLoader) FindCompiledTemplate(_ string) (*template.CompiledTem
 
 func (l stdTemplateLoader) Load(thread *starlark.Thread, module string) (starlark.StringDict, error) {
 	api := yttlibrary.NewAPI(l.compiledTemplate.TplReplaceNode,
-		yttlibrary.NewDataModule(defaultInput(), nil), nil)
+		yttlibrary.NewDataModule(defaultInput(), nil), nil, yttlibrary.NewTimeModule(nil))
 	return api.FindModule(strings.TrimPrefix(module, "@ytt:"))
 }
 
@@ -16,11 +16,12 @@ type API struct {
 }
 
 func NewAPI(replaceNodeFunc tplcore.StarlarkFunc, dataMod DataModule,
-	libraryMod starlark.StringDict) API {
+	libraryMod starlark.StringDict, timeMod TimeModule) API {
 
 	return API{map[string]starlark.StringDict{
 		"assert": AssertAPI,
 		"regexp": RegexpAPI,
+		"ip":     IPAPI,
 
 		// Hashes
 		"md5":    MD5API,
@@ -30,6 +31,7 @@ func NewAPI(replaceNodeFunc tplcore.StarlarkFunc, dataMod DataModule,
 		"base64": Base64API,
 		"json":   JSONAPI,
 		"yaml":   YAMLAPI,
+		"toml":   TOMLAPI,
 		"url":    URLAPI,
 
 		// Templating
@@ -43,6 +45,10 @@ func NewAPI(replaceNodeFunc tplcore.StarlarkFunc, dataMod DataModule,
 
 		// Versioning
 		"version": VersionAPI,
+		"semver":  SemverAPI,
+
+		// Dates and durations
+		"time": timeMod.AsModule(),
 
 		"library": libraryMod,
 	}}
@@ -5,10 +5,16 @@ package yttlibrary
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
+	"github.com/k14s/difflib"
 	"github.com/k14s/starlark-go/starlark"
 	"github.com/k14s/starlark-go/starlarkstruct"
+	"github.com/k14s/starlark-go/syntax"
+	"github.com/k14s/ytt/pkg/orderedmap"
 	"github.com/k14s/ytt/pkg/template/core"
+	"github.com/k14s/ytt/pkg/yamlmeta"
 )
 
 var (
@@ -16,7 +22,14 @@ var (
 		"assert": &starlarkstruct.Module{
 			Name: "assert",
 			Members: starlark.StringDict{
-				"fail": starlark.NewBuiltin("assert.fail", core.ErrWrapper(assertModule{}.Fail)),
+				"fail":    starlark.NewBuiltin("assert.fail", core.ErrWrapper(assertModule{}.Fail)),
+				"equals":  starlark.NewBuiltin("assert.equals", core.ErrWrapper(assertModule{}.Equals)),
+				"try_to":  starlark.NewBuiltin("assert.try_to", core.ErrWrapper(assertModule{}.TryTo)),
+				"min":     starlark.NewBuiltin("assert.min", core.ErrWrapper(assertModule{}.Min)),
+				"max":     starlark.NewBuiltin("assert.max", core.ErrWrapper(assertModule{}.Max)),
+				"min_len": starlark.NewBuiltin("assert.min_len", core.ErrWrapper(assertModule{}.MinLen)),
+				"max_len": starlark.NewBuiltin("assert.max_len", core.ErrWrapper(assertModule{}.MaxLen)),
+				"one_of":  starlark.NewBuiltin("assert.one_of", core.ErrWrapper(assertModule{}.OneOf)),
 			},
 		},
 	}
@@ -36,3 +49,323 @@ func (b assertModule) Fail(thread *starlark.Thread, f *starlark.Builtin, args st
 
 	return starlark.None, fmt.Errorf("fail: %s", val)
 }
+
+// Equals compares values structurally (maps regardless of key order, but with keys of different
+// types being different; yamlfragments by their contents) and reports differences as a diff of
+// their YAML representation
+func (b assertModule) Equals(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if args.Len() != 2 {
+		return starlark.None, fmt.Errorf("expected exactly two arguments (expected and actual values)")
+	}
+
+	expected, err := b.asComparableValue(args.Index(0))
+	if err != nil {
+		return starlark.None, fmt.Errorf("expected value: %s", err)
+	}
+
+	actual, err := b.asComparableValue(args.Index(1))
+	if err != nil {
+		return starlark.None, fmt.Errorf("actual value: %s", err)
+	}
+
+	equal, err := starlark.Equal(core.NewGoValue(expected).AsStarlarkValue(), core.NewGoValue(actual).AsStarlarkValue())
+	if err != nil {
+		return starlark.None, err
+	}
+	if equal {
+		return starlark.None, nil
+	}
+
+	expectedYAML, err := b.asSortedYAML(expected)
+	if err != nil {
+		return starlark.None, err
+	}
+
+	actualYAML, err := b.asSortedYAML(actual)
+	if err != nil {
+		return starlark.None, err
+	}
+
+	var diff strings.Builder
+	for _, rec := range difflib.Diff(strings.Split(expectedYAML, "\n"), strings.Split(actualYAML, "\n")) {
+		if len(rec.Payload) > 0 || rec.Delta != difflib.Common {
+			diff.WriteString(fmt.Sprintf("\n  %s %s", rec.Delta, rec.Payload))
+		}
+	}
+
+	return starlark.None, fmt.Errorf("values are not equal (- expected, + actual):%s", diff.String())
+}
+
+// asComparableValue converts val into Go value (e.g. yamlfragments into their contents)
+func (b assertModule) asComparableValue(val starlark.Value) (interface{}, error) {
+	err := b.checkComparable(val)
+	if err != nil {
+		return nil, err
+	}
+	return yamlmeta.NewGoFromAST(core.NewStarlarkValue(val).AsGoValue()), nil
+}
+
+// checkComparable ensures that val (including values nested within it) can be converted into Go value
+func (b assertModule) checkComparable(val starlark.Value) error {
+	switch typedVal := val.(type) {
+	case core.StarlarkValueToGoValueConversion:
+		return nil
+
+	case starlark.NoneType, starlark.Bool, starlark.String, starlark.Int, starlark.Float:
+		return nil
+
+	case *starlark.Dict:
+		for _, item := range typedVal.Items() {
+			for _, itemVal := range item {
+				if err := b.checkComparable(itemVal); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+
+	case *starlark.List, starlark.Tuple, *starlark.Set:
+		iter := typedVal.(starlark.Iterable).Iterate()
+		defer iter.Done()
+
+		var x starlark.Value
+		for iter.Next(&x) {
+			if err := b.checkComparable(x); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case *core.StarlarkStruct, *starlarkstruct.Struct:
+		structVal := typedVal.(starlark.HasAttrs)
+		for _, name := range structVal.AttrNames() {
+			attrVal, err := structVal.Attr(name)
+			if err != nil {
+				return err
+			}
+			if err := b.checkComparable(attrVal); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("values of type %s cannot be compared", val.Type())
+	}
+}
+
+// asSortedYAML serializes value with sorted map keys (keeping types of keys)
+func (b assertModule) asSortedYAML(val interface{}) (string, error) {
+	docSet := &yamlmeta.DocumentSet{Items: []*yamlmeta.Document{{Value: b.sortMapKeys(val)}}}
+
+	valBs, err := docSet.AsBytes()
+	if err != nil {
+		return "", err
+	}
+	return string(valBs), nil
+}
+
+func (b assertModule) sortMapKeys(val interface{}) interface{} {
+	switch typedVal := val.(type) {
+	case *orderedmap.Map:
+		keys := typedVal.Keys()
+		sort.SliceStable(keys, func(i, j int) bool {
+			return fmt.Sprintf("%v", keys[i]) < fmt.Sprintf("%v", keys[j])
+		})
+
+		result := orderedmap.NewMap()
+		for _, key := range keys {
+			keyVal, _ := typedVal.Get(key)
+			result.Set(key, b.sortMapKeys(keyVal))
+		}
+		return result
+
+	case []interface{}:
+		var result []interface{}
+		for _, item := range typedVal {
+			result = append(result, b.sortMapKeys(item))
+		}
+		return result
+
+	default:
+		return val
+	}
+}
+
+// TryTo calls given function and returns (value, None) if it succeeded, or (None, error message) if it failed
+func (b assertModule) TryTo(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if args.Len() != 1 {
+		return starlark.None, fmt.Errorf("expected exactly one argument")
+	}
+
+	fn, ok := args.Index(0).(starlark.Callable)
+	if !ok {
+		return starlark.None, fmt.Errorf("expected argument to be a function, but was %s", args.Index(0).Type())
+	}
+
+	val, err := starlark.Call(thread, fn, nil, nil)
+	if err != nil {
+		return starlark.Tuple{starlark.None, starlark.String(err.Error())}, nil
+	}
+
+	return starlark.Tuple{val, starlark.None}, nil
+}
+
+func (b assertModule) Min(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	return b.comparisonValidator(f.Name(), syntax.GE, "at least", args)
+}
+
+func (b assertModule) Max(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	return b.comparisonValidator(f.Name(), syntax.LE, "at most", args)
+}
+
+func (b assertModule) MinLen(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	return b.lengthValidator(f.Name(), func(l, limit int64) bool { return l >= limit }, "at least", args)
+}
+
+func (b assertModule) MaxLen(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	return b.lengthValidator(f.Name(), func(l, limit int64) bool { return l <= limit }, "at most", args)
+}
+
+// OneOf accepts allowed values either as arguments or as a single list (or tuple)
+func (b assertModule) OneOf(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if args.Len() == 0 {
+		return starlark.None, fmt.Errorf("expected at least one argument")
+	}
+
+	allowed := args
+	if args.Len() == 1 {
+		switch typedArg := args.Index(0).(type) {
+		case *starlark.List:
+			allowed = nil
+			for i := 0; i < typedArg.Len(); i++ {
+				allowed = append(allowed, typedArg.Index(i))
+			}
+		case starlark.Tuple:
+			allowed = typedArg
+		}
+	}
+
+	allowedList := starlark.NewList(append([]starlark.Value{}, allowed...))
+
+	return &assertValidator{
+		name: fmt.Sprintf("%s(%s)", f.Name(), allowedList.String()),
+		checkFunc: func(val starlark.Value) (string, error) {
+			for _, allowedVal := range allowed {
+				equal, err := starlark.Equal(val, allowedVal)
+				if err != nil {
+					return "", err
+				}
+				if equal {
+					return "", nil
+				}
+			}
+			return fmt.Sprintf("expected value to be one of %s, but was %s", allowedList.String(), val.String()), nil
+		},
+	}, nil
+}
+
+func (b assertModule) comparisonValidator(name string, op syntax.Token, opDesc string, args starlark.Tuple) (starlark.Value, error) {
+	if args.Len() != 1 {
+		return starlark.None, fmt.Errorf("expected exactly one argument")
+	}
+
+	limit := args.Index(0)
+
+	return &assertValidator{
+		name: fmt.Sprintf("%s(%s)", name, limit.String()),
+		checkFunc: func(val starlark.Value) (string, error) {
+			ok, err := starlark.Compare(op, val, limit)
+			if err != nil {
+				return "", err
+			}
+			if !ok {
+				return fmt.Sprintf("expected value to be %s %s, but was %s", opDesc, limit.String(), val.String()), nil
+			}
+			return "", nil
+		},
+	}, nil
+}
+
+func (b assertModule) lengthValidator(name string, cmpFunc func(int64, int64) bool, opDesc string, args starlark.Tuple) (starlark.Value, error) {
+	if args.Len() != 1 {
+		return starlark.None, fmt.Errorf("expected exactly one argument")
+	}
+
+	limit, err := core.NewStarlarkValue(args.Index(0)).AsInt64()
+	if err != nil {
+		return starlark.None, err
+	}
+
+	return &assertValidator{
+		name: fmt.Sprintf("%s(%d)", name, limit),
+		checkFunc: func(val starlark.Value) (string, error) {
+			length := starlark.Len(val)
+			if length < 0 {
+				return "", fmt.Errorf("expected value to have a length, but was %s", val.Type())
+			}
+			if !cmpFunc(int64(length), limit) {
+				return fmt.Sprintf("expected length to be %s %d, but was %d", opDesc, limit, length), nil
+			}
+			return "", nil
+		},
+	}, nil
+}
+
+// assertValidator is a reusable predicate: calling it returns whether value satisfies it,
+// while its check method fails with a description of the violation
+type assertValidator struct {
+	name      string
+	checkFunc func(starlark.Value) (string, error) // returns violation (empty if none)
+}
+
+var _ starlark.Callable = (*assertValidator)(nil)
+var _ starlark.HasAttrs = (*assertValidator)(nil)
+
+func (v *assertValidator) String() string       { return v.name }
+func (v *assertValidator) Type() string         { return "assert.validator" }
+func (v *assertValidator) Freeze()              {}
+func (v *assertValidator) Truth() starlark.Bool { return true }
+func (v *assertValidator) Hash() (uint32, error) {
+	return 0, fmt.Errorf("unhashable type: %s", v.Type())
+}
+func (v *assertValidator) Name() string { return v.name }
+
+func (v *assertValidator) CallInternal(thread *starlark.Thread, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if args.Len() != 1 || len(kwargs) != 0 {
+		return starlark.None, fmt.Errorf("%s: expected exactly one argument", v.name)
+	}
+
+	violation, err := v.checkFunc(args.Index(0))
+	if err != nil {
+		return starlark.None, fmt.Errorf("%s: %s", v.name, err)
+	}
+
+	return starlark.Bool(len(violation) == 0), nil
+}
+
+func (v *assertValidator) AttrNames() []string { return []string{"check"} }
+
+// returns (nil, nil) if attribute not present
+func (v *assertValidator) Attr(name string) (starlark.Value, error) {
+	if name != "check" {
+		return nil, nil
+	}
+	return starlark.NewBuiltin(v.name+".check", core.ErrWrapper(v.check)), nil
+}
+
+func (v *assertValidator) check(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if args.Len() != 1 {
+		return starlark.None, fmt.Errorf("expected exactly one argument")
+	}
+
+	violation, err := v.checkFunc(args.Index(0))
+	if err != nil {
+		return starlark.None, err
+	}
+	if len(violation) > 0 {
+		return starlark.None, fmt.Errorf("%s", violation)
+	}
+
+	return starlark.None, nil
+}
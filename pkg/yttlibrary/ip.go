@@ -0,0 +1,316 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package yttlibrary
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/k14s/starlark-go/starlarkstruct"
+	"github.com/k14s/starlark-go/syntax"
+	"github.com/k14s/ytt/pkg/template/core"
+)
+
+const (
+	ipAddrType = "ip.addr"
+	ipCIDRType = "ip.cidr"
+)
+
+var (
+	IPAPI = starlark.StringDict{
+		"ip": &starlarkstruct.Module{
+			Name: "ip",
+			Members: starlark.StringDict{
+				"parse_addr": starlark.NewBuiltin("ip.parse_addr", core.ErrWrapper(ipModule{}.ParseAddr)),
+				"parse_cidr": starlark.NewBuiltin("ip.parse_cidr", core.ErrWrapper(ipModule{}.ParseCIDR)),
+			},
+		},
+	}
+)
+
+type ipModule struct{}
+
+func (b ipModule) ParseAddr(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if args.Len() != 1 {
+		return starlark.None, fmt.Errorf("expected exactly one argument")
+	}
+
+	str, err := core.NewStarlarkValue(args.Index(0)).AsString()
+	if err != nil {
+		return starlark.None, err
+	}
+
+	addr, err := parseIPAddr(str)
+	if err != nil {
+		return starlark.None, err
+	}
+
+	return addr, nil
+}
+
+// ParseCIDR returns the network given in CIDR notation (see parseIPCIDR)
+func (b ipModule) ParseCIDR(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if args.Len() != 1 {
+		return starlark.None, fmt.Errorf("expected exactly one argument")
+	}
+
+	str, err := core.NewStarlarkValue(args.Index(0)).AsString()
+	if err != nil {
+		return starlark.None, err
+	}
+
+	cidr, err := parseIPCIDR(str)
+	if err != nil {
+		return starlark.None, err
+	}
+
+	return cidr, nil
+}
+
+func parseIPAddr(str string) (*IPAddrValue, error) {
+	ip := net.ParseIP(str)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address: %s", str)
+	}
+	return newIPAddrValue(ip), nil
+}
+
+// parseIPCIDR keeps network part of given CIDR, i.e. host bits are dropped
+// (e.g. 10.0.5.1/16 becomes 10.0.0.0/16); use parse_addr to keep an address
+func parseIPCIDR(str string) (*IPCIDRValue, error) {
+	_, ipNet, err := net.ParseCIDR(str)
+	if err != nil {
+		return nil, err
+	}
+	return &IPCIDRValue{ipNet}, nil
+}
+
+// IPAddrValue is an IPv4 or IPv6 address
+type IPAddrValue struct {
+	ip net.IP
+}
+
+var _ starlark.Value = (*IPAddrValue)(nil)
+var _ starlark.HasAttrs = (*IPAddrValue)(nil)
+var _ starlark.Comparable = (*IPAddrValue)(nil)
+var _ core.StarlarkValueToGoValueConversion = (*IPAddrValue)(nil)
+
+func newIPAddrValue(ip net.IP) *IPAddrValue {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	return &IPAddrValue{ip}
+}
+
+func (v *IPAddrValue) String() string         { return v.ip.String() }
+func (v *IPAddrValue) Type() string           { return ipAddrType }
+func (v *IPAddrValue) Freeze()                {}
+func (v *IPAddrValue) Truth() starlark.Bool   { return true }
+func (v *IPAddrValue) Hash() (uint32, error)  { return starlark.String(v.String()).Hash() }
+func (v *IPAddrValue) AsGoValue() interface{} { return v.String() }
+
+func (v *IPAddrValue) CompareSameType(op syntax.Token, y starlark.Value, depth int) (bool, error) {
+	return compareIPStrings(op, v.String(), y.(*IPAddrValue).String(), ipAddrType)
+}
+
+func (v *IPAddrValue) AttrNames() []string {
+	return []string{"is_ipv4", "is_ipv6", "string"}
+}
+
+// returns (nil, nil) if attribute not present
+func (v *IPAddrValue) Attr(name string) (starlark.Value, error) {
+	switch name {
+	case "is_ipv4":
+		return starlark.Bool(v.isIPv4()), nil
+	case "is_ipv6":
+		return starlark.Bool(!v.isIPv4()), nil
+	case "string":
+		return ipMethod(ipAddrType, name, func() (starlark.Value, error) { return starlark.String(v.String()), nil }), nil
+	}
+	return nil, nil
+}
+
+func (v *IPAddrValue) isIPv4() bool { return len(v.ip) == net.IPv4len }
+
+// IPCIDRValue is an IPv4 or IPv6 network given in CIDR notation
+type IPCIDRValue struct {
+	ipNet *net.IPNet
+}
+
+var _ starlark.Value = (*IPCIDRValue)(nil)
+var _ starlark.HasAttrs = (*IPCIDRValue)(nil)
+var _ starlark.Comparable = (*IPCIDRValue)(nil)
+var _ core.StarlarkValueToGoValueConversion = (*IPCIDRValue)(nil)
+
+func (v *IPCIDRValue) String() string         { return v.ipNet.String() }
+func (v *IPCIDRValue) Type() string           { return ipCIDRType }
+func (v *IPCIDRValue) Freeze()                {}
+func (v *IPCIDRValue) Truth() starlark.Bool   { return true }
+func (v *IPCIDRValue) Hash() (uint32, error)  { return starlark.String(v.String()).Hash() }
+func (v *IPCIDRValue) AsGoValue() interface{} { return v.String() }
+
+func (v *IPCIDRValue) CompareSameType(op syntax.Token, y starlark.Value, depth int) (bool, error) {
+	return compareIPStrings(op, v.String(), y.(*IPCIDRValue).String(), ipCIDRType)
+}
+
+func (v *IPCIDRValue) AttrNames() []string {
+	return []string{"contains", "host", "is_ipv4", "is_ipv6", "netmask", "prefix_length", "string", "subnet"}
+}
+
+// returns (nil, nil) if attribute not present
+func (v *IPCIDRValue) Attr(name string) (starlark.Value, error) {
+	switch name {
+	case "is_ipv4":
+		return starlark.Bool(v.isIPv4()), nil
+	case "is_ipv6":
+		return starlark.Bool(!v.isIPv4()), nil
+	case "netmask":
+		return newIPAddrValue(net.IP(v.ipNet.Mask)), nil
+	case "prefix_length":
+		ones, _ := v.ipNet.Mask.Size()
+		return starlark.MakeInt(ones), nil
+	case "string":
+		return ipMethod(ipCIDRType, name, func() (starlark.Value, error) { return starlark.String(v.String()), nil }), nil
+	case "contains":
+		return starlark.NewBuiltin(ipCIDRType+"."+name, core.ErrWrapper(v.contains)), nil
+	case "subnet":
+		return starlark.NewBuiltin(ipCIDRType+"."+name, core.ErrWrapper(v.subnet)), nil
+	case "host":
+		return starlark.NewBuiltin(ipCIDRType+"."+name, core.ErrWrapper(v.host)), nil
+	}
+	return nil, nil
+}
+
+func (v *IPCIDRValue) isIPv4() bool { return len(v.ipNet.IP) == net.IPv4len }
+
+// contains accepts an address or a network (as a value or a string)
+func (v *IPCIDRValue) contains(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if args.Len() != 1 {
+		return starlark.None, fmt.Errorf("expected exactly one argument")
+	}
+
+	other := args.Index(0)
+
+	if str, ok := other.(starlark.String); ok {
+		var err error
+		if strings.Contains(string(str), "/") {
+			other, err = parseIPCIDR(string(str))
+		} else {
+			other, err = parseIPAddr(string(str))
+		}
+		if err != nil {
+			return starlark.None, err
+		}
+	}
+
+	switch typedOther := other.(type) {
+	case *IPAddrValue:
+		return starlark.Bool(v.ipNet.Contains(typedOther.ip)), nil
+
+	case *IPCIDRValue:
+		ones, bits := v.ipNet.Mask.Size()
+		otherOnes, otherBits := typedOther.ipNet.Mask.Size()
+		return starlark.Bool(bits == otherBits && otherOnes >= ones && v.ipNet.Contains(typedOther.ipNet.IP)), nil
+
+	default:
+		return starlark.None, fmt.Errorf("expected argument to be an %s, %s or string, but was %s", ipAddrType, ipCIDRType, other.Type())
+	}
+}
+
+// subnet returns index-th network within this one, with prefix extended by newbits
+// (e.g. subnet(8, 2) of 10.0.0.0/16 is 10.0.2.0/24)
+func (v *IPCIDRValue) subnet(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if args.Len() != 2 {
+		return starlark.None, fmt.Errorf("expected exactly two arguments (newbits and index)")
+	}
+
+	newBits, err := core.NewStarlarkValue(args.Index(0)).AsInt64()
+	if err != nil {
+		return starlark.None, err
+	}
+
+	index, err := core.NewStarlarkValue(args.Index(1)).AsInt64()
+	if err != nil {
+		return starlark.None, err
+	}
+
+	ones, bits := v.ipNet.Mask.Size()
+	if newBits < 0 || int64(ones)+newBits > int64(bits) {
+		return starlark.None, fmt.Errorf("expected newbits to be between 0 and %d for prefix length %d, but was %d", bits-ones, ones, newBits)
+	}
+
+	newOnes := ones + int(newBits)
+	count := new(big.Int).Lsh(big.NewInt(1), uint(newBits))
+	if index < 0 || big.NewInt(index).Cmp(count) >= 0 {
+		return starlark.None, fmt.Errorf("expected index to be between 0 and %s for %d new bits, but was %d",
+			new(big.Int).Sub(count, big.NewInt(1)), newBits, index)
+	}
+
+	offset := new(big.Int).Lsh(big.NewInt(index), uint(bits-newOnes))
+	ip := ipFromInt(new(big.Int).Add(ipToInt(v.ipNet.IP), offset), len(v.ipNet.IP))
+
+	return &IPCIDRValue{&net.IPNet{IP: ip, Mask: net.CIDRMask(newOnes, bits)}}, nil
+}
+
+// host returns n-th address within this network; negative n counts from the end
+// (e.g. host(1) of 10.0.0.0/24 is 10.0.0.1, host(-2) is 10.0.0.254)
+func (v *IPCIDRValue) host(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if args.Len() != 1 {
+		return starlark.None, fmt.Errorf("expected exactly one argument")
+	}
+
+	num, err := core.NewStarlarkValue(args.Index(0)).AsInt64()
+	if err != nil {
+		return starlark.None, err
+	}
+
+	ones, bits := v.ipNet.Mask.Size()
+	count := new(big.Int).Lsh(big.NewInt(1), uint(bits-ones))
+
+	offset := big.NewInt(num)
+	if num < 0 {
+		offset.Add(offset, count)
+	}
+	if offset.Sign() < 0 || offset.Cmp(count) >= 0 {
+		return starlark.None, fmt.Errorf("expected host number to be between -%s and %s for network %s, but was %d",
+			count, new(big.Int).Sub(count, big.NewInt(1)), v.String(), num)
+	}
+
+	return newIPAddrValue(ipFromInt(new(big.Int).Add(ipToInt(v.ipNet.IP), offset), len(v.ipNet.IP))), nil
+}
+
+func ipMethod(typeName, name string, resultFunc func() (starlark.Value, error)) *starlark.Builtin {
+	return starlark.NewBuiltin(typeName+"."+name, core.ErrWrapper(
+		func(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			if args.Len() != 0 || len(kwargs) != 0 {
+				return starlark.None, fmt.Errorf("expected no arguments")
+			}
+			return resultFunc()
+		}))
+}
+
+func compareIPStrings(op syntax.Token, x, y string, typeName string) (bool, error) {
+	switch op {
+	case syntax.EQL:
+		return x == y, nil
+	case syntax.NEQ:
+		return x != y, nil
+	default:
+		return false, fmt.Errorf("%s values can only be compared for equality", typeName)
+	}
+}
+
+func ipToInt(ip net.IP) *big.Int {
+	return new(big.Int).SetBytes(ip)
+}
+
+func ipFromInt(num *big.Int, length int) net.IP {
+	bs := num.Bytes()
+	ip := make(net.IP, length)
+	copy(ip[length-len(bs):], bs)
+	return ip
+}
@@ -0,0 +1,259 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package yttlibrary
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	semver "github.com/hashicorp/go-version"
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/k14s/starlark-go/starlarkstruct"
+	"github.com/k14s/starlark-go/syntax"
+	"github.com/k14s/ytt/pkg/template/core"
+)
+
+const (
+	semverType = "semver.version"
+
+	semverBumpMajor = "bump_major"
+	semverBumpMinor = "bump_minor"
+	semverBumpPatch = "bump_patch"
+)
+
+var (
+	SemverAPI = starlark.StringDict{
+		"semver": &starlarkstruct.Module{
+			Name: "semver",
+			Members: starlark.StringDict{
+				"parse":         starlark.NewBuiltin("semver.parse", core.ErrWrapper(semverModule{}.Parse)),
+				"compare":       starlark.NewBuiltin("semver.compare", core.ErrWrapper(semverModule{}.Compare)),
+				"satisfies":     starlark.NewBuiltin("semver.satisfies", core.ErrWrapper(semverModule{}.Satisfies)),
+				semverBumpMajor: starlark.NewBuiltin("semver."+semverBumpMajor, core.ErrWrapper(semverModule{}.bumpFunc(semverBumpMajor))),
+				semverBumpMinor: starlark.NewBuiltin("semver."+semverBumpMinor, core.ErrWrapper(semverModule{}.bumpFunc(semverBumpMinor))),
+				semverBumpPatch: starlark.NewBuiltin("semver."+semverBumpPatch, core.ErrWrapper(semverModule{}.bumpFunc(semverBumpPatch))),
+			},
+		},
+	}
+
+	// operator (optional) followed by a version, e.g. '>=1.19', '< 1.22' or '1.2.3'
+	semverConstraintPart = regexp.MustCompile(`(~>|!=|>=|<=|=|>|<)?\s*v?[0-9][^\s,|]*`)
+)
+
+type semverModule struct{}
+
+func (b semverModule) Parse(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if args.Len() != 1 {
+		return starlark.None, fmt.Errorf("expected exactly one argument")
+	}
+
+	return semverArg(args.Index(0))
+}
+
+// Compare returns -1, 0 or 1 if first version is lower, equal or greater than second
+func (b semverModule) Compare(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if args.Len() != 2 {
+		return starlark.None, fmt.Errorf("expected exactly two arguments")
+	}
+
+	v1, err := semverArg(args.Index(0))
+	if err != nil {
+		return starlark.None, err
+	}
+
+	v2, err := semverArg(args.Index(1))
+	if err != nil {
+		return starlark.None, err
+	}
+
+	return starlark.MakeInt(v1.version.Compare(v2.version)), nil
+}
+
+func (b semverModule) Satisfies(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if args.Len() != 2 {
+		return starlark.None, fmt.Errorf("expected exactly two arguments (version and constraint)")
+	}
+
+	ver, err := semverArg(args.Index(0))
+	if err != nil {
+		return starlark.None, err
+	}
+
+	constraintStr, err := core.NewStarlarkValue(args.Index(1)).AsString()
+	if err != nil {
+		return starlark.None, err
+	}
+
+	satisfied, err := semverSatisfies(ver.version, constraintStr)
+	if err != nil {
+		return starlark.None, err
+	}
+
+	return starlark.Bool(satisfied), nil
+}
+
+func (b semverModule) bumpFunc(segment string) core.StarlarkFunc {
+	return func(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		if args.Len() != 1 {
+			return starlark.None, fmt.Errorf("expected exactly one argument")
+		}
+
+		ver, err := semverArg(args.Index(0))
+		if err != nil {
+			return starlark.None, err
+		}
+
+		return ver.bump(segment)
+	}
+}
+
+// semverArg accepts either a version string or a parsed version
+func semverArg(val starlark.Value) (*SemverValue, error) {
+	if typedVal, ok := val.(*SemverValue); ok {
+		return typedVal, nil
+	}
+
+	str, err := core.NewStarlarkValue(val).AsString()
+	if err != nil {
+		return nil, fmt.Errorf("expected version to be a string or %s, but was %s", semverType, val.Type())
+	}
+
+	ver, err := semver.NewVersion(str)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version '%s': %s", str, err)
+	}
+
+	return &SemverValue{ver}, nil
+}
+
+// semverSatisfies checks version against constraint whose parts are separated
+// by spaces or commas (all must match), e.g. '>=1.19 <1.22';
+// alternatives are separated by '||' (any must match), e.g. '<1.0 || >=2.0'
+func semverSatisfies(ver *semver.Version, constraintStr string) (bool, error) {
+	for _, alternative := range strings.Split(constraintStr, "||") {
+		parts := semverConstraintPart.FindAllString(alternative, -1)
+
+		leftover := semverConstraintPart.ReplaceAllString(alternative, "")
+		if len(parts) == 0 || len(strings.Trim(leftover, " \t,")) > 0 {
+			return false, fmt.Errorf("invalid constraint '%s' (e.g. '>=1.19 <1.22' or '~> 1.2')", constraintStr)
+		}
+
+		constraints, err := semver.NewConstraint(strings.Join(parts, ","))
+		if err != nil {
+			return false, fmt.Errorf("invalid constraint '%s': %s", constraintStr, err)
+		}
+
+		if constraints.Check(ver) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// SemverValue is a parsed version (leading 'v' and missing segments are allowed, e.g. v1.19)
+type SemverValue struct {
+	version *semver.Version
+}
+
+var _ starlark.Value = (*SemverValue)(nil)
+var _ starlark.HasAttrs = (*SemverValue)(nil)
+var _ starlark.Comparable = (*SemverValue)(nil)
+var _ core.StarlarkValueToGoValueConversion = (*SemverValue)(nil)
+
+func (v *SemverValue) String() string         { return v.version.String() }
+func (v *SemverValue) Type() string           { return semverType }
+func (v *SemverValue) Freeze()                {}
+func (v *SemverValue) Truth() starlark.Bool   { return true }
+func (v *SemverValue) AsGoValue() interface{} { return v.String() }
+
+// Hash ignores metadata since it does not take part in comparison
+func (v *SemverValue) Hash() (uint32, error) {
+	segments := v.version.Segments64()
+	core := fmt.Sprintf("%d.%d.%d-%s", segments[0], segments[1], segments[2], v.version.Prerelease())
+	return starlark.String(core).Hash()
+}
+
+func (v *SemverValue) CompareSameType(op syntax.Token, y starlark.Value, depth int) (bool, error) {
+	cmp := v.version.Compare(y.(*SemverValue).version)
+	switch op {
+	case syntax.EQL:
+		return cmp == 0, nil
+	case syntax.NEQ:
+		return cmp != 0, nil
+	case syntax.LT:
+		return cmp < 0, nil
+	case syntax.LE:
+		return cmp <= 0, nil
+	case syntax.GT:
+		return cmp > 0, nil
+	case syntax.GE:
+		return cmp >= 0, nil
+	default:
+		return false, fmt.Errorf("unsupported comparison %s for %s", op, semverType)
+	}
+}
+
+func (v *SemverValue) AttrNames() []string {
+	return []string{semverBumpMajor, semverBumpMinor, semverBumpPatch,
+		"major", "metadata", "minor", "patch", "prerelease", "string"}
+}
+
+// returns (nil, nil) if attribute not present
+func (v *SemverValue) Attr(name string) (starlark.Value, error) {
+	segments := v.version.Segments64()
+
+	switch name {
+	case "major":
+		return starlark.MakeInt64(segments[0]), nil
+	case "minor":
+		return starlark.MakeInt64(segments[1]), nil
+	case "patch":
+		return starlark.MakeInt64(segments[2]), nil
+	case "prerelease":
+		return starlark.String(v.version.Prerelease()), nil
+	case "metadata":
+		return starlark.String(v.version.Metadata()), nil
+	case "string":
+		return starlark.NewBuiltin(semverType+"."+name, core.ErrWrapper(
+			func(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+				if args.Len() != 0 || len(kwargs) != 0 {
+					return starlark.None, fmt.Errorf("expected no arguments")
+				}
+				return starlark.String(v.String()), nil
+			})), nil
+	case semverBumpMajor, semverBumpMinor, semverBumpPatch:
+		return starlark.NewBuiltin(semverType+"."+name, core.ErrWrapper(
+			func(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+				if args.Len() != 0 || len(kwargs) != 0 {
+					return starlark.None, fmt.Errorf("expected no arguments")
+				}
+				return v.bump(name)
+			})), nil
+	}
+	return nil, nil
+}
+
+// bump increments given segment, resetting lower segments and dropping prerelease and metadata
+func (v *SemverValue) bump(segment string) (*SemverValue, error) {
+	segments := v.version.Segments64()
+	major, minor, patch := segments[0], segments[1], segments[2]
+
+	switch segment {
+	case semverBumpMajor:
+		major, minor, patch = major+1, 0, 0
+	case semverBumpMinor:
+		minor, patch = minor+1, 0
+	case semverBumpPatch:
+		patch++
+	default:
+		panic(fmt.Sprintf("Unknown version segment '%s'", segment))
+	}
+
+	ver, err := semver.NewVersion(fmt.Sprintf("%d.%d.%d", major, minor, patch))
+	if err != nil {
+		return nil, err
+	}
+	return &SemverValue{ver}, nil
+}
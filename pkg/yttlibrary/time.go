@@ -0,0 +1,359 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package yttlibrary
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/k14s/starlark-go/starlarkstruct"
+	"github.com/k14s/starlark-go/syntax"
+	"github.com/k14s/ytt/pkg/template/core"
+)
+
+const (
+	timeDurationType = "time.duration"
+	timeTimeType     = "time.time"
+)
+
+// TimeModule provides durations and timestamps. Current time is only
+// available when clock is given, so that by default output is reproducible.
+type TimeModule struct {
+	clock func() time.Time
+}
+
+// NewTimeModule disallows access to current time if clock is nil
+func NewTimeModule(clock func() time.Time) TimeModule {
+	return TimeModule{clock}
+}
+
+func (b TimeModule) AsModule() starlark.StringDict {
+	return starlark.StringDict{
+		"time": &starlarkstruct.Module{
+			Name: "time",
+			Members: starlark.StringDict{
+				"parse_duration": starlark.NewBuiltin("time.parse_duration", core.ErrWrapper(b.ParseDuration)),
+				"parse_time":     starlark.NewBuiltin("time.parse_time", core.ErrWrapper(b.ParseTime)),
+				"now":            starlark.NewBuiltin("time.now", core.ErrWrapper(b.Now)),
+			},
+		},
+	}
+}
+
+// ParseDuration accepts Go durations (e.g. 90s, 5m, 2h30m)
+func (b TimeModule) ParseDuration(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if args.Len() != 1 {
+		return starlark.None, fmt.Errorf("expected exactly one argument")
+	}
+
+	str, err := core.NewStarlarkValue(args.Index(0)).AsString()
+	if err != nil {
+		return starlark.None, err
+	}
+
+	dur, err := time.ParseDuration(str)
+	if err != nil {
+		return starlark.None, err
+	}
+
+	return TimeDurationValue{dur}, nil
+}
+
+// ParseTime accepts RFC3339 timestamps, unless layout (in Go format) is given
+func (b TimeModule) ParseTime(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if args.Len() != 1 {
+		return starlark.None, fmt.Errorf("expected exactly one argument")
+	}
+
+	str, err := core.NewStarlarkValue(args.Index(0)).AsString()
+	if err != nil {
+		return starlark.None, err
+	}
+
+	layout, err := timeLayoutArg(kwargs)
+	if err != nil {
+		return starlark.None, err
+	}
+
+	t, err := time.Parse(layout, str)
+	if err != nil {
+		return starlark.None, err
+	}
+
+	return TimeValue{t}, nil
+}
+
+func (b TimeModule) Now(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if args.Len() != 0 {
+		return starlark.None, fmt.Errorf("expected no arguments")
+	}
+
+	if b.clock == nil {
+		return starlark.None, fmt.Errorf("access to current time is not allowed " +
+			"since it makes output non-reproducible (hint: enable it via --allow-clock flag)")
+	}
+
+	return TimeValue{b.clock().UTC()}, nil
+}
+
+func timeLayoutArg(kwargs []starlark.Tuple) (string, error) {
+	for _, kwarg := range kwargs {
+		name, err := core.NewStarlarkValue(kwarg.Index(0)).AsString()
+		if err != nil {
+			return "", err
+		}
+		if name != "layout" {
+			return "", fmt.Errorf("unexpected keyword argument '%s'", name)
+		}
+		return core.NewStarlarkValue(kwarg.Index(1)).AsString()
+	}
+	return time.RFC3339Nano, nil
+}
+
+// TimeDurationValue supports arithmetic with other durations (+, -) and integers (*, /)
+type TimeDurationValue struct {
+	dur time.Duration
+}
+
+var _ starlark.Value = TimeDurationValue{}
+var _ starlark.HasAttrs = TimeDurationValue{}
+var _ starlark.HasBinary = TimeDurationValue{}
+var _ starlark.Comparable = TimeDurationValue{}
+var _ core.StarlarkValueToGoValueConversion = TimeDurationValue{}
+
+func (v TimeDurationValue) String() string         { return v.dur.String() }
+func (v TimeDurationValue) Type() string           { return timeDurationType }
+func (v TimeDurationValue) Freeze()                {}
+func (v TimeDurationValue) Truth() starlark.Bool   { return v.dur != 0 }
+func (v TimeDurationValue) Hash() (uint32, error)  { return starlark.MakeInt64(int64(v.dur)).Hash() }
+func (v TimeDurationValue) AsGoValue() interface{} { return v.String() }
+
+func (v TimeDurationValue) CompareSameType(op syntax.Token, y starlark.Value, depth int) (bool, error) {
+	return compareTimeInts(op, int64(v.dur), int64(y.(TimeDurationValue).dur), timeDurationType)
+}
+
+// returns (nil, nil) if operation is not supported
+func (v TimeDurationValue) Binary(op syntax.Token, y starlark.Value, side starlark.Side) (starlark.Value, error) {
+	switch typedY := y.(type) {
+	case TimeDurationValue:
+		switch op {
+		case syntax.PLUS:
+			sum := v.dur + typedY.dur
+			if (sum > v.dur) != (typedY.dur > 0) {
+				return nil, fmt.Errorf("sum of %s and %s is out of range of %s", v, typedY, timeDurationType)
+			}
+			return TimeDurationValue{sum}, nil
+		case syntax.MINUS:
+			x, y := v, typedY
+			if side == starlark.Right {
+				x, y = typedY, v
+			}
+			diff := x.dur - y.dur
+			if (diff < x.dur) != (y.dur > 0) {
+				return nil, fmt.Errorf("difference between %s and %s is out of range of %s", x, y, timeDurationType)
+			}
+			return TimeDurationValue{diff}, nil
+		}
+
+	case starlark.Int:
+		num, ok := typedY.Int64()
+		if !ok {
+			return nil, fmt.Errorf("expected int64 value")
+		}
+		switch {
+		case op == syntax.STAR:
+			product := v.dur * time.Duration(num)
+			if num != 0 && (product/time.Duration(num) != v.dur || (num == -1 && v.dur == math.MinInt64)) {
+				return nil, fmt.Errorf("product of %s and %d is out of range of %s", v, num, timeDurationType)
+			}
+			return TimeDurationValue{product}, nil
+		case (op == syntax.SLASH || op == syntax.SLASHSLASH) && side == starlark.Left:
+			if num == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			if num == -1 && v.dur == math.MinInt64 {
+				return nil, fmt.Errorf("quotient of %s and %d is out of range of %s", v, num, timeDurationType)
+			}
+			return TimeDurationValue{v.dur / time.Duration(num)}, nil
+		}
+	}
+	return nil, nil
+}
+
+func (v TimeDurationValue) AttrNames() []string {
+	return []string{"hours", "milliseconds", "minutes", "seconds", "string"}
+}
+
+// returns (nil, nil) if attribute not present
+func (v TimeDurationValue) Attr(name string) (starlark.Value, error) {
+	var resultFunc func() starlark.Value
+
+	switch name {
+	case "hours":
+		resultFunc = func() starlark.Value { return starlark.Float(v.dur.Hours()) }
+	case "minutes":
+		resultFunc = func() starlark.Value { return starlark.Float(v.dur.Minutes()) }
+	case "seconds":
+		resultFunc = func() starlark.Value { return starlark.Float(v.dur.Seconds()) }
+	case "milliseconds":
+		resultFunc = func() starlark.Value { return starlark.MakeInt64(v.dur.Milliseconds()) }
+	case "string":
+		resultFunc = func() starlark.Value { return starlark.String(v.String()) }
+	default:
+		return nil, nil
+	}
+
+	return timeMethod(timeDurationType, name, resultFunc), nil
+}
+
+// TimeValue is a point in time; it's formatted as RFC3339 by default
+type TimeValue struct {
+	t time.Time
+}
+
+var _ starlark.Value = TimeValue{}
+var _ starlark.HasAttrs = TimeValue{}
+var _ starlark.HasBinary = TimeValue{}
+var _ starlark.Comparable = TimeValue{}
+var _ core.StarlarkValueToGoValueConversion = TimeValue{}
+
+func (v TimeValue) String() string         { return v.t.Format(time.RFC3339Nano) }
+func (v TimeValue) Type() string           { return timeTimeType }
+func (v TimeValue) Freeze()                {}
+func (v TimeValue) Truth() starlark.Bool   { return true }
+func (v TimeValue) AsGoValue() interface{} { return v.String() }
+
+// Hash (and comparison) avoid UnixNano since it overflows for years outside of 1678-2262 (e.g. 9999-12-31)
+func (v TimeValue) Hash() (uint32, error) {
+	return starlark.Tuple{starlark.MakeInt64(v.t.Unix()), starlark.MakeInt(v.t.Nanosecond())}.Hash()
+}
+
+func (v TimeValue) CompareSameType(op syntax.Token, y starlark.Value, depth int) (bool, error) {
+	yt := y.(TimeValue).t
+	switch op {
+	case syntax.EQL:
+		return v.t.Equal(yt), nil
+	case syntax.NEQ:
+		return !v.t.Equal(yt), nil
+	case syntax.LT:
+		return v.t.Before(yt), nil
+	case syntax.LE:
+		return !v.t.After(yt), nil
+	case syntax.GT:
+		return v.t.After(yt), nil
+	case syntax.GE:
+		return !v.t.Before(yt), nil
+	default:
+		return false, fmt.Errorf("unsupported comparison %s for %s", op, timeTimeType)
+	}
+}
+
+// returns (nil, nil) if operation is not supported
+func (v TimeValue) Binary(op syntax.Token, y starlark.Value, side starlark.Side) (starlark.Value, error) {
+	switch typedY := y.(type) {
+	case TimeDurationValue:
+		switch {
+		case op == syntax.PLUS:
+			return TimeValue{v.t.Add(typedY.dur)}, nil
+		case op == syntax.MINUS && side == starlark.Left:
+			// negating the smallest duration overflows back to itself
+			if typedY.dur == math.MinInt64 {
+				return nil, fmt.Errorf("difference between %s and %s is out of range of %s", v, typedY, timeTimeType)
+			}
+			return TimeValue{v.t.Add(-typedY.dur)}, nil
+		}
+
+	case TimeValue:
+		if op == syntax.MINUS && side == starlark.Left {
+			// Sub saturates instead of failing when difference does not fit into a duration (~292 years)
+			dur := v.t.Sub(typedY.t)
+			if !typedY.t.Add(dur).Equal(v.t) {
+				return nil, fmt.Errorf("difference between %s and %s is out of range of %s", v, typedY, timeDurationType)
+			}
+			return TimeDurationValue{dur}, nil
+		}
+	}
+	return nil, nil
+}
+
+func (v TimeValue) AttrNames() []string {
+	return []string{"day", "format", "hour", "minute", "month", "second", "string", "unix", "weekday", "year"}
+}
+
+// returns (nil, nil) if attribute not present
+func (v TimeValue) Attr(name string) (starlark.Value, error) {
+	switch name {
+	case "year":
+		return starlark.MakeInt(v.t.Year()), nil
+	case "month":
+		return starlark.MakeInt(int(v.t.Month())), nil
+	case "day":
+		return starlark.MakeInt(v.t.Day()), nil
+	case "hour":
+		return starlark.MakeInt(v.t.Hour()), nil
+	case "minute":
+		return starlark.MakeInt(v.t.Minute()), nil
+	case "second":
+		return starlark.MakeInt(v.t.Second()), nil
+	case "weekday":
+		// 0 is Sunday (as in cron schedules)
+		return starlark.MakeInt(int(v.t.Weekday())), nil
+	case "unix":
+		return timeMethod(timeTimeType, name, func() starlark.Value { return starlark.MakeInt64(v.t.Unix()) }), nil
+	case "string":
+		return timeMethod(timeTimeType, name, func() starlark.Value { return starlark.String(v.String()) }), nil
+	case "format":
+		return starlark.NewBuiltin(timeTimeType+"."+name, core.ErrWrapper(v.format)), nil
+	}
+	return nil, nil
+}
+
+// format uses Go layout (e.g. "2006-01-02"), or RFC3339 if not given
+func (v TimeValue) format(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if args.Len() > 1 || len(kwargs) > 0 {
+		return starlark.None, fmt.Errorf("expected zero or one argument (layout)")
+	}
+
+	layout := time.RFC3339
+	if args.Len() == 1 {
+		var err error
+		layout, err = core.NewStarlarkValue(args.Index(0)).AsString()
+		if err != nil {
+			return starlark.None, err
+		}
+	}
+
+	return starlark.String(v.t.Format(layout)), nil
+}
+
+func timeMethod(typeName, name string, resultFunc func() starlark.Value) *starlark.Builtin {
+	return starlark.NewBuiltin(typeName+"."+name, core.ErrWrapper(
+		func(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			if args.Len() != 0 || len(kwargs) != 0 {
+				return starlark.None, fmt.Errorf("expected no arguments")
+			}
+			return resultFunc(), nil
+		}))
+}
+
+func compareTimeInts(op syntax.Token, x, y int64, typeName string) (bool, error) {
+	switch op {
+	case syntax.EQL:
+		return x == y, nil
+	case syntax.NEQ:
+		return x != y, nil
+	case syntax.LT:
+		return x < y, nil
+	case syntax.LE:
+		return x <= y, nil
+	case syntax.GT:
+		return x > y, nil
+	case syntax.GE:
+		return x >= y, nil
+	default:
+		return false, fmt.Errorf("unsupported comparison %s for %s", op, typeName)
+	}
+}
@@ -0,0 +1,369 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package yttlibrary
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/k14s/starlark-go/starlarkstruct"
+	"github.com/k14s/ytt/pkg/orderedmap"
+	"github.com/k14s/ytt/pkg/template/core"
+	"github.com/k14s/ytt/pkg/yamlmeta"
+)
+
+var (
+	TOMLAPI = starlark.StringDict{
+		"toml": &starlarkstruct.Module{
+			Name: "toml",
+			Members: starlark.StringDict{
+				"encode": starlark.NewBuiltin("toml.encode", core.ErrWrapper(tomlModule{}.Encode)),
+				"decode": starlark.NewBuiltin("toml.decode", core.ErrWrapper(tomlModule{}.Decode)),
+			},
+		},
+	}
+
+	tomlBareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+)
+
+type tomlModule struct{}
+
+func (b tomlModule) Encode(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if args.Len() != 1 {
+		return starlark.None, fmt.Errorf("expected exactly one argument")
+	}
+
+	indent, err := core.Int64Arg(kwargs, "indent")
+	if err != nil {
+		return starlark.None, err
+	}
+	if indent < 0 {
+		return starlark.None, fmt.Errorf("expected indent to be non-negative, but was %d", indent)
+	}
+
+	val := core.NewStarlarkValue(args.Index(0)).AsGoValue()
+	val = yamlmeta.NewGoFromAST(val)
+
+	table, ok := val.(*orderedmap.Map)
+	if !ok {
+		return starlark.None, fmt.Errorf("expected value to be a map (TOML document is a table), but was %T", val)
+	}
+
+	enc := &tomlEncoder{indent: strings.Repeat(" ", int(indent))}
+
+	err = enc.table(nil, table)
+	if err != nil {
+		return starlark.None, err
+	}
+
+	return starlark.String(enc.buf.String()), nil
+}
+
+func (b tomlModule) Decode(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if args.Len() != 1 {
+		return starlark.None, fmt.Errorf("expected exactly one argument")
+	}
+
+	valEncoded, err := core.NewStarlarkValue(args.Index(0)).AsString()
+	if err != nil {
+		return starlark.None, err
+	}
+
+	valDecoded, err := DecodeTOML([]byte(valEncoded))
+	if err != nil {
+		return starlark.None, err
+	}
+
+	return core.NewGoValue(valDecoded).AsStarlarkValue(), nil
+}
+
+// DecodeTOML decodes TOML document into ordered maps (keeping keys in order
+// of the document) and other YAML compatible values (e.g. dates become strings)
+func DecodeTOML(contents []byte) (interface{}, error) {
+	var decoded map[string]interface{}
+
+	md, err := toml.Decode(string(contents), &decoded)
+	if err != nil {
+		return nil, err
+	}
+
+	keyOrder := map[string]int{}
+	for i, key := range md.Keys() {
+		// keys of arrays of tables repeat for each table
+		if _, found := keyOrder[tomlKeyPath(key)]; !found {
+			keyOrder[tomlKeyPath(key)] = i
+		}
+	}
+
+	return tomlDecoding{keyOrder}.fromTOMLValue(nil, decoded), nil
+}
+
+type tomlDecoding struct {
+	keyOrder map[string]int
+}
+
+func (d tomlDecoding) fromTOMLValue(path []string, val interface{}) interface{} {
+	switch typedVal := val.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(typedVal))
+		for k := range typedVal {
+			keys = append(keys, k)
+		}
+		d.sortKeys(path, keys)
+
+		result := orderedmap.NewMap()
+		for _, k := range keys {
+			result.Set(k, d.fromTOMLValue(append(path[:len(path):len(path)], k), typedVal[k]))
+		}
+		return result
+
+	case []map[string]interface{}:
+		result := []interface{}{}
+		for _, item := range typedVal {
+			result = append(result, d.fromTOMLValue(path, item))
+		}
+		return result
+
+	case []interface{}:
+		for i, item := range typedVal {
+			typedVal[i] = d.fromTOMLValue(path, item)
+		}
+		return typedVal
+
+	case int64:
+		return int(typedVal)
+
+	case time.Time:
+		return typedVal.Format(time.RFC3339Nano)
+
+	default:
+		return typedVal
+	}
+}
+
+// sortKeys orders keys as they appeared in the document
+// (keys unknown to decoder metadata go last, sorted by name)
+func (d tomlDecoding) sortKeys(path []string, keys []string) {
+	sort.Slice(keys, func(i, j int) bool {
+		iIdx, iFound := d.keyOrder[tomlKeyPath(append(path[:len(path):len(path)], keys[i]))]
+		jIdx, jFound := d.keyOrder[tomlKeyPath(append(path[:len(path):len(path)], keys[j]))]
+		switch {
+		case iFound && jFound:
+			return iIdx < jIdx
+		case iFound != jFound:
+			return iFound
+		default:
+			return keys[i] < keys[j]
+		}
+	})
+}
+
+func tomlKeyPath(key []string) string {
+	// NUL cannot appear within keys, unlike dots
+	return strings.Join(key, "\x00")
+}
+
+type tomlEncoder struct {
+	indent string
+	buf    strings.Builder
+}
+
+// table writes key/value pairs of a table followed by its sub-tables
+// (TOML requires all pairs of a table to come before any sub-table)
+func (e *tomlEncoder) table(path []string, table *orderedmap.Map) error {
+	type subTable struct {
+		Path  []string
+		Value interface{}
+	}
+	var subTables []subTable
+
+	err := table.IterateErr(func(k, v interface{}) error {
+		keyPath := append(path[:len(path):len(path)], fmt.Sprintf("%v", k))
+
+		switch {
+		case e.isTable(v) || e.isArrayOfTables(v):
+			subTables = append(subTables, subTable{keyPath, v})
+			return nil
+		default:
+			encodedVal, err := e.value(keyPath, v)
+			if err != nil {
+				return err
+			}
+			e.line(len(path), fmt.Sprintf("%s = %s", e.key(keyPath[len(keyPath)-1]), encodedVal))
+			return nil
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range subTables {
+		if typedVal, ok := sub.Value.(*orderedmap.Map); ok {
+			e.header(sub.Path, "[%s]")
+			err := e.table(sub.Path, typedVal)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		for _, item := range sub.Value.([]interface{}) {
+			e.header(sub.Path, "[[%s]]")
+			err := e.table(sub.Path, item.(*orderedmap.Map))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (e *tomlEncoder) header(path []string, format string) {
+	if e.buf.Len() > 0 {
+		e.buf.WriteString("\n")
+	}
+	var keys []string
+	for _, key := range path {
+		keys = append(keys, e.key(key))
+	}
+	e.line(len(path)-1, fmt.Sprintf(format, strings.Join(keys, ".")))
+}
+
+func (e *tomlEncoder) line(depth int, str string) {
+	e.buf.WriteString(strings.Repeat(e.indent, depth) + str + "\n")
+}
+
+func (e *tomlEncoder) isTable(val interface{}) bool {
+	_, ok := val.(*orderedmap.Map)
+	return ok
+}
+
+func (e *tomlEncoder) isArrayOfTables(val interface{}) bool {
+	items, ok := val.([]interface{})
+	if !ok || len(items) == 0 {
+		return false
+	}
+	for _, item := range items {
+		if !e.isTable(item) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *tomlEncoder) key(key string) string {
+	if tomlBareKey.MatchString(key) {
+		return key
+	}
+	return e.string(key)
+}
+
+// value encodes value inline (maps become inline tables)
+func (e *tomlEncoder) value(path []string, val interface{}) (string, error) {
+	switch typedVal := val.(type) {
+	case nil:
+		return "", fmt.Errorf("expected value of key '%s' to not be null (TOML does not support null values)", strings.Join(path, "."))
+	case string:
+		return e.string(typedVal), nil
+	case bool:
+		return strconv.FormatBool(typedVal), nil
+	case int:
+		return strconv.FormatInt(int64(typedVal), 10), nil
+	case int64:
+		return strconv.FormatInt(typedVal, 10), nil
+	case uint64:
+		return strconv.FormatUint(typedVal, 10), nil
+	case float64:
+		return e.float(typedVal), nil
+
+	case []interface{}:
+		var items []string
+		for _, item := range typedVal {
+			encodedItem, err := e.value(path, item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, encodedItem)
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+
+	case *orderedmap.Map:
+		var items []string
+		err := typedVal.IterateErr(func(k, v interface{}) error {
+			keyPath := append(path[:len(path):len(path)], fmt.Sprintf("%v", k))
+			encodedItem, err := e.value(keyPath, v)
+			if err != nil {
+				return err
+			}
+			items = append(items, fmt.Sprintf("%s = %s", e.key(keyPath[len(keyPath)-1]), encodedItem))
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+		if len(items) == 0 {
+			return "{}", nil
+		}
+		return "{ " + strings.Join(items, ", ") + " }", nil
+
+	default:
+		return "", fmt.Errorf("expected value of key '%s' to be TOML compatible, but was %T", strings.Join(path, "."), val)
+	}
+}
+
+func (e *tomlEncoder) float(val float64) string {
+	switch {
+	case math.IsNaN(val):
+		return "nan"
+	case math.IsInf(val, 1):
+		return "inf"
+	case math.IsInf(val, -1):
+		return "-inf"
+	}
+	str := strconv.FormatFloat(val, 'g', -1, 64)
+	if !strings.ContainsAny(str, ".e") {
+		// otherwise value would be decoded as an integer
+		str += ".0"
+	}
+	return str
+}
+
+func (e *tomlEncoder) string(val string) string {
+	var result strings.Builder
+	result.WriteString(`"`)
+	for _, r := range val {
+		switch r {
+		case '"':
+			result.WriteString(`\"`)
+		case '\\':
+			result.WriteString(`\\`)
+		case '\b':
+			result.WriteString(`\b`)
+		case '\t':
+			result.WriteString(`\t`)
+		case '\n':
+			result.WriteString(`\n`)
+		case '\f':
+			result.WriteString(`\f`)
+		case '\r':
+			result.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&result, `\u%04X`, r)
+			} else {
+				result.WriteRune(r)
+			}
+		}
+	}
+	result.WriteString(`"`)
+	return result.String()
+}
@@ -48,17 +48,15 @@ func (b versionModule) RequireAtLeast(thread *starlark.Thread, f *starlark.Built
 		return starlark.None, fmt.Errorf("version string '%s' must be a valid semver", val)
 	}
 
-	userConstraint, err := semver.NewConstraint(">=" + val)
+	yttVersion, err := semver.NewVersion(version.Version)
 	if err != nil {
 		return starlark.None, err
 	}
 
-	yttVersion, err := semver.NewVersion(version.Version)
+	satisfied, err := semverSatisfies(yttVersion, ">="+val)
 	if err != nil {
 		return starlark.None, err
 	}
-
-	satisfied := userConstraint.Check(yttVersion)
 	if !satisfied {
 		return starlark.None, fmt.Errorf("ytt version '%s' does not meet the minimum required version '%s'", version.Version, val)
 	}